@@ -0,0 +1,179 @@
+package progressio
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Errors returned when parsing sizes
+var (
+	ErrInvalidSize   = errors.New("invalid size")
+	ErrUnknownUnit   = errors.New("unknown size unit")
+	ErrAmbiguousUnit = errors.New("ambiguous size unit")
+)
+
+// unitMatch is a unit name of a SizeSystem matching the unit being parsed
+type unitMatch struct {
+	name       string
+	multiplier int64
+}
+
+// ParseSize parses a human readable size like "1.5GiB", "500 MB" or "10
+// kilobytes" into the amount of bytes, using the Metric, IEC and JEDEC size
+// systems. See ParseSizeSystem for the details.
+func ParseSize(s string) (int64, error) {
+	return ParseSizeSystem(s, Metric, IEC, JEDEC)
+}
+
+// ParseSizeSystem parses a human readable size into the amount of bytes, using
+// the units of the specified size systems. The number can have a decimal
+// fraction, and can be followed by a unit, optionally separated by a space.
+// Without unit, the number is the amount of bytes.
+//
+// Short unit names ("kB", "MiB") are case sensitive. If they don't match
+// exactly, they are matched case insensitively, which fails with
+// ErrAmbiguousUnit if the unit matches several units with different values,
+// e.g. "kb" matching both the metric "kB" and JEDEC "KB", or "mb" matching both
+// the metric and JEDEC "MB". Long unit names ("kilobyte", "mebibytes") are case
+// insensitive and can be plural.
+//
+// If the same name is used by several size systems with different values (e.g.
+// "MB" in the Metric and JEDEC systems), the first system specified wins.
+func ParseSizeSystem(s string, systems ...SizeSystem) (int64, error) {
+	str := strings.TrimSpace(s)
+	i := strings.IndexFunc(str, func(r rune) bool {
+		return !unicode.IsDigit(r) && r != '.' && r != '-' && r != '+'
+	})
+	if i < 0 {
+		i = len(str)
+	}
+	num, unit := str[:i], strings.TrimSpace(str[i:])
+	if num == "" {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidSize, s)
+	}
+	multiplier := Byte
+	if unit != "" {
+		var err error
+		if multiplier, err = parseUnit(unit, systems); err != nil {
+			return 0, fmt.Errorf("%w: %q", err, s)
+		}
+	}
+	ret, err := multiply(num, multiplier)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", err, s)
+	}
+	return ret, nil
+}
+
+// parseUnit returns the multiplier of the unit in the size systems.
+func parseUnit(unit string, systems []SizeSystem) (int64, error) {
+	var exact, folded []unitMatch
+	for _, ss := range systems {
+		mult := Byte
+		for i := range ss.Names {
+			if i > 0 {
+				mult *= ss.MultiPlier
+			}
+			name, short := ss.Names[i], ss.Shorts[i]
+			if unit == short {
+				exact = append(exact, unitMatch{short, mult})
+			} else if strings.EqualFold(unit, short) {
+				folded = append(folded, unitMatch{short, mult})
+			}
+			if strings.EqualFold(unit, name) || strings.EqualFold(unit, name+"s") {
+				exact = append(exact, unitMatch{name, mult})
+			}
+		}
+	}
+	if len(exact) > 0 {
+		return exact[0].multiplier, nil
+	}
+	if len(folded) == 0 {
+		return 0, ErrUnknownUnit
+	}
+	for _, m := range folded[1:] {
+		if m.multiplier != folded[0].multiplier {
+			return 0, fmt.Errorf("%w: could be %s (%d bytes) or %s (%d bytes)", ErrAmbiguousUnit,
+				folded[0].name, folded[0].multiplier, m.name, m.multiplier)
+		}
+	}
+	return folded[0].multiplier, nil
+}
+
+// multiply multiplies the decimal number by the multiplier, rounding the result
+// to a whole amount of bytes. The integer part is handled separately to keep
+// the full precision for large sizes.
+func multiply(num string, multiplier int64) (int64, error) {
+	neg := false
+	if num[0] == '-' || num[0] == '+' {
+		neg = num[0] == '-'
+		num = num[1:]
+	}
+	ipart, fpart := num, ""
+	if i := strings.IndexByte(num, '.'); i >= 0 {
+		ipart, fpart = num[:i], num[i+1:]
+	}
+	if ipart == "" && fpart == "" {
+		return 0, ErrInvalidSize
+	}
+	var whole int64
+	if ipart != "" {
+		var err error
+		if whole, err = strconv.ParseInt(ipart, 10, 64); err != nil || whole < 0 {
+			return 0, ErrInvalidSize
+		}
+	}
+	if whole > math.MaxInt64/multiplier {
+		return 0, fmt.Errorf("%w: out of range", ErrInvalidSize)
+	}
+	ret := whole * multiplier
+	if fpart != "" {
+		frac, err := strconv.ParseFloat("0."+fpart, 64)
+		if err != nil {
+			return 0, ErrInvalidSize
+		}
+		add := int64(math.Round(frac * float64(multiplier)))
+		if ret > math.MaxInt64-add {
+			return 0, fmt.Errorf("%w: out of range", ErrInvalidSize)
+		}
+		ret += add
+	}
+	if neg {
+		ret = -ret
+	}
+	return ret, nil
+}
+
+// SizeFlag is a size in bytes which implements the flag.Value interface, so
+// human readable sizes can be used as command line flags:
+//
+//	limit := progressio.SizeFlag(10 * progressio.MebiByte)
+//	flag.Var(&limit, "limit", "maximum size")
+type SizeFlag int64
+
+// String returns the size formatted using the IEC size system.
+func (f *SizeFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return FormatSize(IEC, int64(*f), true)
+}
+
+// Set parses the size using ParseSize.
+func (f *SizeFlag) Set(s string) error {
+	n, err := ParseSize(s)
+	if err != nil {
+		return err
+	}
+	*f = SizeFlag(n)
+	return nil
+}
+
+// Get returns the size in bytes as an int64, it implements flag.Getter.
+func (f *SizeFlag) Get() interface{} {
+	return int64(*f)
+}
@@ -0,0 +1,94 @@
+package progressio
+
+import (
+	"errors"
+	"flag"
+	"io/ioutil"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr error
+	}{
+		{"0", 0, nil},
+		{"1234", 1234, nil},
+		{"  42  ", 42, nil},
+		{"10B", 10, nil},
+		{"10 bytes", 10, nil},
+		{"1kB", KiloByte, nil},
+		{"1KB", JEDECKiloByte, nil},
+		{"1KiB", KibiByte, nil},
+		{"1.5GiB", GibiByte + GibiByte/2, nil},
+		{"500 MB", 500 * MegaByte, nil},
+		{"2 TB", 2 * TeraByte, nil},
+		{"1PiB", PebiByte, nil},
+		{"0.5 kibibyte", 512, nil},
+		{"3 Megabytes", 3 * MegaByte, nil},
+		{"1 TEBIBYTES", TebiByte, nil},
+		{"1 kilobyte", KiloByte, nil},
+		{"1mib", MebiByte, nil},
+		{"1 gb", 0, ErrAmbiguousUnit},
+		{".5KiB", 512, nil},
+		{"1.0005kB", 1001, nil},
+		{"-1MiB", -MebiByte, nil},
+		{"1 kb", 0, ErrAmbiguousUnit},
+		{"1mb", 0, ErrAmbiguousUnit},
+		{"1 parsec", 0, ErrUnknownUnit},
+		{"MiB", 0, ErrInvalidSize},
+		{"", 0, ErrInvalidSize},
+		{"1.2.3MB", 0, ErrInvalidSize},
+		{".", 0, ErrInvalidSize},
+		{"9000PiB", 0, ErrInvalidSize},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.in)
+		if !errors.Is(err, tt.wantErr) || (tt.wantErr != nil) != (err != nil) {
+			t.Errorf("ParseSize(%q) error = %v, want %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseSizeSystem(t *testing.T) {
+	if got, err := ParseSizeSystem("1MB", JEDEC); err != nil || got != JEDECMegaByte {
+		t.Errorf("ParseSizeSystem(JEDEC) = %d, %v, want %d", got, err, JEDECMegaByte)
+	}
+	if _, err := ParseSizeSystem("1MiB", Metric); !errors.Is(err, ErrUnknownUnit) {
+		t.Errorf("ParseSizeSystem(Metric) error = %v, want %v", err, ErrUnknownUnit)
+	}
+	// Formatted sizes can be parsed back
+	for _, ss := range []SizeSystem{Metric, IEC, JEDEC} {
+		size := 3 * ss.MultiPlier * ss.MultiPlier * ss.MultiPlier
+		for _, short := range []bool{true, false} {
+			s := FormatSize(ss, size, short)
+			if got, err := ParseSizeSystem(s, ss); err != nil || got != size {
+				t.Errorf("ParseSizeSystem(%q) = %d, %v, want %d", s, got, err, size)
+			}
+		}
+	}
+}
+
+func TestSizeFlag(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	limit := SizeFlag(KibiByte)
+	fs.Var(&limit, "limit", "limit")
+	if err := fs.Parse([]string{"-limit", "1.5GiB"}); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if int64(limit) != GibiByte+GibiByte/2 {
+		t.Errorf("limit = %d, want %d", limit, GibiByte+GibiByte/2)
+	}
+	if limit.String() != "1.50GiB" {
+		t.Errorf("String() = %q, want %q", limit.String(), "1.50GiB")
+	}
+	if err := fs.Parse([]string{"-limit", "lots"}); err == nil {
+		t.Errorf("Parse() expected an error")
+	}
+}
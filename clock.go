@@ -0,0 +1,25 @@
+package progressio
+
+import "time"
+
+// Clock is the source of the current time used to calculate the progress.
+// It can be replaced with WithClock, e.g. to get deterministic results in tests.
+type Clock interface {
+	Now() time.Time
+}
+
+// TimerClock is a Clock which can also wait. The rate limiting waits using the
+// Clock of the wrapper if it is a TimerClock, and using the system time
+// otherwise.
+type TimerClock interface {
+	Clock
+	// After returns a channel on which the time is sent once d has elapsed
+	After(d time.Duration) <-chan time.Time
+}
+
+// systemClock is the Clock using the system time
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
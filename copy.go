@@ -0,0 +1,88 @@
+package progressio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// CopySummary summarizes a copy made with Copy, CopyN or CopyBuffer.
+type CopySummary struct {
+	Written  int64         // Bytes copied
+	Duration time.Duration // Time the copy took
+	SpeedAvg int64         // Bytes/sec average over the copy, pauses left out
+}
+
+// String returns the string representation of the summary.
+func (s CopySummary) String() string {
+	return fmt.Sprintf("%s in %s (%s/s)",
+		FormatSize(IEC, s.Written, true), FormatDuration(s.Duration), FormatSize(IEC, s.SpeedAvg, true))
+}
+
+// Copy copies from src to dst like io.Copy, reporting the progress of the copy
+// of size bytes (<= 0 if unknown) as configured by the options, e.g. WithCallback
+// or WithChannel. Copy blocks until the copy completes, so the channels have to
+// be read from another goroutine. Use WithContext to make the copy cancellable.
+//
+// If dst implements io.ReaderFrom or src implements io.WriterTo, it is used to
+// copy the data, see ProgressReader.WriteTo. If src ends before size bytes were
+// copied, Copy returns ErrIncomplete, like the final update.
+func Copy(dst io.Writer, src io.Reader, size int64, opts ...Option) (CopySummary, error) {
+	return copyProgress(dst, src, nil, size, opts)
+}
+
+// CopyN copies n bytes from src to dst like io.CopyN, reporting the progress of
+// the copy as configured by the options, see Copy. It returns io.EOF if src
+// contains less than n bytes.
+func CopyN(dst io.Writer, src io.Reader, n int64, opts ...Option) (CopySummary, error) {
+	s, err := copyProgress(dst, io.LimitReader(src, n), nil, n, opts)
+	if s.Written < n && (err == nil || errors.Is(err, ErrIncomplete)) {
+		err = io.EOF
+	}
+	return s, err
+}
+
+// CopyBuffer is identical to Copy, but stages the data through buf like
+// io.CopyBuffer if neither io.WriterTo nor io.ReaderFrom is available.
+func CopyBuffer(dst io.Writer, src io.Reader, buf []byte, size int64, opts ...Option) (CopySummary, error) {
+	if buf != nil && len(buf) == 0 {
+		panic("empty buffer in CopyBuffer")
+	}
+	return copyProgress(dst, src, buf, size, opts)
+}
+
+// copyProgress copies src to dst using ProgressReader.WriteTo, or through buf
+// if no fast path is available, and returns the summary taken from the final
+// update. If the copy itself succeeded, the error is the outcome of the
+// transfer, e.g. ErrIncomplete.
+func copyProgress(dst io.Writer, src io.Reader, buf []byte, size int64, opts []Option) (CopySummary, error) {
+	var final Progress
+	p := mkIoProgress(size, append(opts[:len(opts):len(opts)], WithCallback(func(prog Progress) {
+		if !prog.StopTime.IsZero() {
+			final = prog
+		}
+	}))...)
+
+	written, err := int64(0), p.ctxErr()
+	if err == nil {
+		r := &ProgressReader{src, p}
+		_, writerTo := src.(io.WriterTo)
+		_, readerFrom := dst.(io.ReaderFrom)
+		if writerTo || readerFrom || buf == nil {
+			written, err = r.WriteTo(dst)
+		} else {
+			// Read does the bookkeeping, hide WriteTo to use buf
+			written, err = io.CopyBuffer(dst, struct{ io.Reader }{r}, buf)
+		}
+	}
+	p.stopProgress(err)
+	if err == nil {
+		err = p.Wait()
+	}
+	return CopySummary{
+		Written:  written,
+		Duration: final.Elapsed(),
+		SpeedAvg: final.SpeedAvg,
+	}, err
+}
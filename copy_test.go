@@ -0,0 +1,142 @@
+package progressio
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bartmeuris/progressio/progressiotest"
+)
+
+// slowReader is an io.Reader advancing the clock on every read
+type slowReader struct {
+	r     io.Reader
+	clock *progressiotest.Clock
+}
+
+func (s *slowReader) Read(b []byte) (int, error) {
+	s.clock.Advance(time.Second)
+	if len(b) > 100 {
+		b = b[:100]
+	}
+	return s.r.Read(b)
+}
+
+func TestCopy(t *testing.T) {
+	data := strings.Repeat("x", 1000)
+	tests := []struct {
+		name string
+		dst  func(*bytes.Buffer) io.Writer
+		src  func(*progressiotest.Clock) io.Reader
+	}{
+		{
+			// bytes.Buffer implements io.ReaderFrom
+			name: "ReaderFrom",
+			dst:  func(b *bytes.Buffer) io.Writer { return b },
+			src: func(c *progressiotest.Clock) io.Reader {
+				return &slowReader{strings.NewReader(data), c}
+			},
+		},
+		{
+			name: "WriterTo",
+			dst:  func(b *bytes.Buffer) io.Writer { return struct{ io.Writer }{b} },
+			src: func(c *progressiotest.Clock) io.Reader {
+				c.Advance(10 * time.Second)
+				return strings.NewReader(data)
+			},
+		},
+		{
+			name: "plain",
+			dst:  func(b *bytes.Buffer) io.Writer { return struct{ io.Writer }{b} },
+			src: func(c *progressiotest.Clock) io.Reader {
+				return &slowReader{strings.NewReader(data), c}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := progressiotest.NewClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+			var buf bytes.Buffer
+			var last Progress
+			s, err := Copy(tt.dst(&buf), tt.src(clock), 1000,
+				WithClock(clock),
+				WithCallback(func(p Progress) { last = p }))
+			if err != nil {
+				t.Fatalf("Copy failed: %v", err)
+			}
+			if buf.String() != data {
+				t.Errorf("Copied %d bytes, want %d", buf.Len(), len(data))
+			}
+			if s.Written != 1000 || last.Transferred != 1000 || last.State != StateDone {
+				t.Errorf("Copy summary %+v, final progress %+v", s, last)
+			}
+			if s.Duration != last.Elapsed() || s.SpeedAvg != last.SpeedAvg {
+				t.Errorf("Summary %+v does not match the final progress %+v", s, last)
+			}
+		})
+	}
+}
+
+func TestCopyN(t *testing.T) {
+	var buf bytes.Buffer
+	s, err := CopyN(&buf, strings.NewReader("some data"), 4)
+	if err != nil || s.Written != 4 || buf.String() != "some" {
+		t.Errorf("CopyN(4) = %+v, %v, copied %q", s, err, buf.String())
+	}
+	buf.Reset()
+	s, err = CopyN(&buf, strings.NewReader("some data"), 20)
+	if err != io.EOF || s.Written != 9 {
+		t.Errorf("CopyN(20) = %+v, %v, want io.EOF", s, err)
+	}
+}
+
+func TestCopyShort(t *testing.T) {
+	var buf bytes.Buffer
+	var last Progress
+	s, err := Copy(&buf, strings.NewReader("abc"), 10, WithCallback(func(p Progress) { last = p }))
+	if err != ErrIncomplete || s.Written != 3 {
+		t.Errorf("Copy = %+v, %v, want ErrIncomplete", s, err)
+	}
+	if last.State != StateFailed || last.Err != ErrIncomplete {
+		t.Errorf("Final progress %v, %v, want failed with ErrIncomplete", last.State, last.Err)
+	}
+}
+
+func TestCopyBuffer(t *testing.T) {
+	var buf bytes.Buffer
+	var calls int
+	s, err := CopyBuffer(struct{ io.Writer }{&buf}, struct{ io.Reader }{strings.NewReader("some data")},
+		make([]byte, 2), -1,
+		WithUpdateInterval(0),
+		WithCallback(func(Progress) { calls++ }))
+	if err != nil || s.Written != 9 || buf.String() != "some data" {
+		t.Errorf("CopyBuffer = %+v, %v, copied %q", s, err, buf.String())
+	}
+	// At least one update per read of 2 bytes, and the final one
+	if calls < 6 {
+		t.Errorf("Got %d updates, want at least 6", calls)
+	}
+}
+
+func TestCopyContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var last Progress
+	_, err := Copy(discardWriter{}, zeroReader{}, -1,
+		WithContext(ctx),
+		WithCallback(func(p Progress) { last = p }))
+	if err != context.Canceled {
+		t.Errorf("Copy error = %v, want %v", err, context.Canceled)
+	}
+	if last.Err != context.Canceled || last.State != StateFailed {
+		t.Errorf("Final progress: %+v", last)
+	}
+}
+
+// discardWriter discards everything, without implementing io.ReaderFrom
+type discardWriter struct{}
+
+func (discardWriter) Write(b []byte) (int, error) { return len(b), nil }
@@ -1,6 +1,8 @@
 package progressio
 
 import "fmt"
+import "strconv"
+import "strings"
 import "time"
 
 // SecondFormatter represents a duration in seconds
@@ -88,3 +90,179 @@ func FormatDuration(dur time.Duration) string {
 func FormatSeconds(seconds int64) string {
 	return SecondFormatter(seconds).String()
 }
+
+// DurationStyle selects how a DurationFormat formats durations
+type DurationStyle int
+
+// The available duration styles
+const (
+	DurationVerbose DurationStyle = iota // "1 hour, 2 minutes, 3 seconds", like FormatDuration
+	DurationCompact                      // "1h2m3s"
+	DurationClock                        // "01:02:03", or "2d 01:02:03" when there are days
+	DurationRounded                      // "about 1 hour 2 minutes"
+)
+
+// The units used by the compact and rounded styles, from most to least significant
+var durationSteps = []struct {
+	d     time.Duration
+	short string
+	long  string
+}{
+	{week, "w", "week"},
+	{24 * time.Hour, "d", "day"},
+	{time.Hour, "h", "hour"},
+	{time.Minute, "m", "minute"},
+	{time.Second, "s", "second"},
+}
+
+// DurationFormat formats durations in one of the DurationStyle styles. The zero
+// value formats durations like FormatDuration does.
+type DurationFormat struct {
+	Style     DurationStyle // The style of the formatted durations
+	Units     int           // The amount of units shown by DurationRounded, 2 if <= 0
+	SubSecond bool          // Show fractions of seconds for durations shorter than a minute
+}
+
+// Format returns the string representation of the duration in the style of the
+// DurationFormat.
+func (f DurationFormat) Format(d time.Duration) string {
+	neg := d < 0
+	if neg {
+		d = -d
+	}
+	sub := f.SubSecond && d < time.Minute
+	var s string
+	switch f.Style {
+	case DurationCompact:
+		s = f.compact(d, sub)
+	case DurationClock:
+		s = f.clock(d, sub)
+	case DurationRounded:
+		// Negative durations read like the verbose style: "about 2 seconds ago"
+		s = f.rounded(d, sub)
+		if neg && s != "0 seconds" {
+			s += " ago"
+		}
+		return s
+	default:
+		if sub {
+			s = fractionSeconds(d) + " second"
+			if d.Round(10*time.Millisecond) != time.Second {
+				s += "s"
+			}
+		} else {
+			s = SecondFormatter(d / time.Second).String()
+		}
+		if neg && s != "0 seconds" {
+			s += " ago"
+		}
+		return s
+	}
+	if neg && s != "0s" && s != "00:00:00" {
+		s = "-" + s
+	}
+	return s
+}
+
+func (f DurationFormat) compact(d time.Duration, sub bool) string {
+	if sub {
+		if d < time.Second {
+			return strconv.FormatInt(int64(d/time.Millisecond), 10) + "ms"
+		}
+		return fractionSeconds(d) + "s"
+	}
+	s := ""
+	secs := d / time.Second * time.Second
+	for _, step := range durationSteps {
+		if n := secs / step.d; n > 0 {
+			s += strconv.FormatInt(int64(n), 10) + step.short
+			secs -= n * step.d
+		}
+	}
+	if s == "" {
+		return "0s"
+	}
+	return s
+}
+
+func (f DurationFormat) clock(d time.Duration, sub bool) string {
+	sf := SecondFormatter(d / time.Second)
+	s := fmt.Sprintf("%02d:%02d:%02d", sf.Hours(), sf.Minutes(), sf.Seconds())
+	if days := int64(sf) / 86400; days > 0 {
+		s = fmt.Sprintf("%dd %s", days, s)
+	}
+	if sub {
+		s += fmt.Sprintf(".%03d", int64(d%time.Second/time.Millisecond))
+	}
+	return s
+}
+
+func (f DurationFormat) rounded(d time.Duration, sub bool) string {
+	if sub {
+		r := d.Round(100 * time.Millisecond)
+		s := strconv.FormatFloat(r.Seconds(), 'f', -1, 64) + " second"
+		if r != time.Second {
+			s += "s"
+		}
+		if r != d {
+			s = "about " + s
+		}
+		return s
+	}
+	units := f.Units
+	if units <= 0 {
+		units = 2
+	}
+	// Round the duration to the least significant unit shown
+	first := len(durationSteps) - 1
+	for i, step := range durationSteps {
+		if d >= step.d {
+			first = i
+			break
+		}
+	}
+	last := first + units - 1
+	if last >= len(durationSteps) {
+		last = len(durationSteps) - 1
+	}
+	r := d.Round(durationSteps[last].d)
+	var parts []string
+	rest := r
+	for _, step := range durationSteps[:last+1] {
+		if n := rest / step.d; n > 0 {
+			parts = append(parts, addCountString("", int64(n), step.long))
+			rest -= n * step.d
+		}
+	}
+	if len(parts) == 0 {
+		parts = []string{"0 seconds"}
+	}
+	s := strings.Join(parts, " ")
+	if r != d {
+		s = "about " + s
+	}
+	return s
+}
+
+// fractionSeconds returns the amount of seconds with up to 2 decimals
+func fractionSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Round(10*time.Millisecond).Seconds(), 'f', -1, 64)
+}
+
+// Compact returns the compact representation of the SecondFormatter instance,
+// like "1h2m3s"
+func (s SecondFormatter) Compact() string {
+	return DurationFormat{Style: DurationCompact}.Format(time.Duration(s) * time.Second)
+}
+
+// Clock returns the clock representation of the SecondFormatter instance, like
+// "01:02:03", prefixed with the amount of days if needed
+func (s SecondFormatter) Clock() string {
+	return DurationFormat{Style: DurationClock}.Format(time.Duration(s) * time.Second)
+}
+
+// Rounded returns the representation of the SecondFormatter instance rounded to
+// the specified amount of most significant units, like "about 1 hour 2 minutes"
+func (s SecondFormatter) Rounded(units int) string {
+	return DurationFormat{Style: DurationRounded, Units: units}.Format(time.Duration(s) * time.Second)
+}
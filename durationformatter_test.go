@@ -0,0 +1,67 @@
+package progressio
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDurationFormat(t *testing.T) {
+	d := func(h, m, s int) time.Duration {
+		return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(s)*time.Second
+	}
+	tests := []struct {
+		name string
+		f    DurationFormat
+		d    time.Duration
+		want string
+	}{
+		{"verbose", DurationFormat{}, d(1, 2, 3), "1 hour, 2 minutes, 3 seconds"},
+		{"verbose negative", DurationFormat{}, -d(0, 2, 0), "2 minutes ago"},
+		{"verbose subsecond", DurationFormat{SubSecond: true}, 1250 * time.Millisecond, "1.25 seconds"},
+		{"verbose subsecond rounded", DurationFormat{SubSecond: true}, 999 * time.Millisecond, "1 second"},
+		{"verbose subsecond long", DurationFormat{SubSecond: true}, d(0, 1, 0) + 250*time.Millisecond, "1 minute"},
+		{"compact", DurationFormat{Style: DurationCompact}, d(1, 2, 3), "1h2m3s"},
+		{"compact days", DurationFormat{Style: DurationCompact}, d(24*9+1, 0, 5), "1w2d1h5s"},
+		{"compact zero", DurationFormat{Style: DurationCompact}, 0, "0s"},
+		{"compact negative", DurationFormat{Style: DurationCompact}, -d(0, 5, 0), "-5m"},
+		{"compact subsecond", DurationFormat{Style: DurationCompact, SubSecond: true}, 350 * time.Millisecond, "350ms"},
+		{"compact subsecond seconds", DurationFormat{Style: DurationCompact, SubSecond: true}, 2345 * time.Millisecond, "2.35s"},
+		{"clock", DurationFormat{Style: DurationClock}, d(1, 2, 3), "01:02:03"},
+		{"clock days", DurationFormat{Style: DurationClock}, d(24*9+1, 2, 3), "9d 01:02:03"},
+		{"clock subsecond", DurationFormat{Style: DurationClock, SubSecond: true}, 1250 * time.Millisecond, "00:00:01.250"},
+		{"clock negative", DurationFormat{Style: DurationClock}, -d(0, 0, 7), "-00:00:07"},
+		{"rounded", DurationFormat{Style: DurationRounded}, d(1, 2, 40), "about 1 hour 3 minutes"},
+		{"rounded exact", DurationFormat{Style: DurationRounded}, d(1, 2, 0), "1 hour 2 minutes"},
+		{"rounded carry", DurationFormat{Style: DurationRounded}, d(1, 59, 40), "about 2 hours"},
+		{"rounded one unit", DurationFormat{Style: DurationRounded, Units: 1}, d(26, 0, 0), "about 1 day"},
+		{"rounded three units", DurationFormat{Style: DurationRounded, Units: 3}, d(1, 2, 3), "1 hour 2 minutes 3 seconds"},
+		{"rounded seconds", DurationFormat{Style: DurationRounded}, 3 * time.Second, "3 seconds"},
+		{"rounded zero", DurationFormat{Style: DurationRounded}, 0, "0 seconds"},
+		{"rounded negative", DurationFormat{Style: DurationRounded}, -d(1, 2, 40), "about 1 hour 3 minutes ago"},
+		{"rounded subsecond", DurationFormat{Style: DurationRounded, SubSecond: true}, 1260 * time.Millisecond, "about 1.3 seconds"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.f.Format(tt.d); got != tt.want {
+				t.Errorf("Format(%v) = %q, want %q", tt.d, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSecondFormatterStyles(t *testing.T) {
+	s := SecondFormatter(3723)
+	if got := s.Compact(); got != "1h2m3s" {
+		t.Errorf("Compact() = %q", got)
+	}
+	if got := s.Clock(); got != "01:02:03" {
+		t.Errorf("Clock() = %q", got)
+	}
+	if got := s.Rounded(1); got != "about 1 hour" {
+		t.Errorf("Rounded(1) = %q", got)
+	}
+	// The compact style can be parsed back
+	if got, err := ParseHumanDuration(s.Compact()); err != nil || got != 3723*time.Second {
+		t.Errorf("ParseHumanDuration(%q) = %v, %v", s.Compact(), got, err)
+	}
+}
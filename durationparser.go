@@ -0,0 +1,136 @@
+package progressio
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ErrInvalidDuration is returned when a duration can not be parsed
+var ErrInvalidDuration = errors.New("invalid duration")
+
+const week = 7 * 24 * time.Hour
+
+// durationUnits maps the unit names accepted by ParseHumanDuration to their value
+var durationUnits = map[string]time.Duration{
+	"w": week, "wk": week, "wks": week, "week": week, "weeks": week,
+	"d": 24 * time.Hour, "day": 24 * time.Hour, "days": 24 * time.Hour,
+	"h": time.Hour, "hr": time.Hour, "hrs": time.Hour, "hour": time.Hour, "hours": time.Hour,
+	"m": time.Minute, "min": time.Minute, "mins": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"s": time.Second, "sec": time.Second, "secs": time.Second, "second": time.Second, "seconds": time.Second,
+	"ms": time.Millisecond,
+}
+
+// ParseHumanDuration parses a duration as formatted by FormatDuration and
+// SecondFormatter.String, like "2 weeks, 3 days, 1 hour", into a time.Duration.
+// The " ago" suffix or a "-" prefix makes the duration negative. Abbreviated
+// forms like "2w 3d 1h", "1h2m3s" or "1 hr 5 mins" are accepted too, the parts
+// can be separated by spaces, commas or "and", and the numbers can have a
+// decimal fraction. The output of every DurationFormat style can be parsed: the
+// "about" prefix of the rounded style is ignored, and the clock style like
+// "2d 01:02:03.250" is recognized by its colons.
+func ParseHumanDuration(s string) (time.Duration, error) {
+	str := strings.ToLower(strings.TrimSpace(s))
+	neg := false
+	if strings.HasSuffix(str, " ago") {
+		neg = true
+		str = strings.TrimSpace(strings.TrimSuffix(str, " ago"))
+	} else if strings.HasPrefix(str, "-") {
+		neg = true
+		str = str[1:]
+	}
+	str = strings.TrimPrefix(str, "about ")
+	var ret float64
+	if strings.Contains(str, ":") {
+		secs, ok := parseClock(str)
+		if !ok {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidDuration, s)
+		}
+		ret = secs * float64(time.Second)
+	} else {
+		var err error
+		if ret, err = parseUnits(str, s); err != nil {
+			return 0, err
+		}
+	}
+	if ret > math.MaxInt64 {
+		return 0, fmt.Errorf("%w: out of range: %q", ErrInvalidDuration, s)
+	}
+	if neg {
+		ret = -ret
+	}
+	return time.Duration(math.Round(ret)), nil
+}
+
+// parseUnits parses the numbers followed by their unit in str, the lowercased
+// duration s, and returns their sum in nanoseconds.
+func parseUnits(str, s string) (float64, error) {
+	isSep := func(r rune) bool { return unicode.IsSpace(r) || r == ',' }
+	var ret float64
+	parts := 0
+	for {
+		str = strings.TrimLeftFunc(str, isSep)
+		if strings.HasPrefix(str, "and") && parts > 0 {
+			str = strings.TrimLeftFunc(str[3:], isSep)
+		}
+		if str == "" {
+			break
+		}
+		i := strings.IndexFunc(str, func(r rune) bool { return !unicode.IsDigit(r) && r != '.' })
+		if i <= 0 {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidDuration, s)
+		}
+		num, err := strconv.ParseFloat(str[:i], 64)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidDuration, s)
+		}
+		str = strings.TrimLeftFunc(str[i:], unicode.IsSpace)
+		j := strings.IndexFunc(str, func(r rune) bool { return !unicode.IsLetter(r) })
+		if j < 0 {
+			j = len(str)
+		}
+		unit, ok := durationUnits[str[:j]]
+		if !ok {
+			return 0, fmt.Errorf("%w: unknown unit %q in %q", ErrInvalidDuration, str[:j], s)
+		}
+		str = str[j:]
+		ret += num * float64(unit)
+		parts++
+	}
+	if parts == 0 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidDuration, s)
+	}
+	return ret, nil
+}
+
+// parseClock parses a duration in the clock style, "01:02:03" with an optional
+// fraction of seconds and amount of days like "2d 01:02:03.250", into seconds.
+func parseClock(s string) (float64, bool) {
+	var days uint64
+	if i := strings.Index(s, "d "); i > 0 {
+		var err error
+		if days, err = strconv.ParseUint(s[:i], 10, 64); err != nil {
+			return 0, false
+		}
+		s = strings.TrimSpace(s[i+2:])
+	}
+	fields := strings.Split(s, ":")
+	if len(fields) != 3 {
+		return 0, false
+	}
+	h, err1 := strconv.ParseUint(fields[0], 10, 64)
+	m, err2 := strconv.ParseUint(fields[1], 10, 64)
+	invalid := func(r rune) bool { return !unicode.IsDigit(r) && r != '.' }
+	if err1 != nil || err2 != nil || m >= 60 || fields[2] == "" || strings.IndexFunc(fields[2], invalid) >= 0 {
+		return 0, false
+	}
+	sec, err := strconv.ParseFloat(fields[2], 64)
+	if err != nil || sec >= 60 {
+		return 0, false
+	}
+	return float64((days*24+h)*60+m)*60 + sec, true
+}
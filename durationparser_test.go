@@ -0,0 +1,97 @@
+package progressio
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseHumanDuration(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"0 seconds", 0, false},
+		{"1 second", time.Second, false},
+		{"2 weeks, 3 days, 1 hour", 2*week + 3*24*time.Hour + time.Hour, false},
+		{"1 hour, 2 minutes, 3 seconds", time.Hour + 2*time.Minute + 3*time.Second, false},
+		{"5 minutes ago", -5 * time.Minute, false},
+		{"1h2m3s", time.Hour + 2*time.Minute + 3*time.Second, false},
+		{"2w 3d", 2*week + 3*24*time.Hour, false},
+		{"1 hr 5 mins", time.Hour + 5*time.Minute, false},
+		{"1 minute and 30 seconds", 90 * time.Second, false},
+		{"1.5 hours", 90 * time.Minute, false},
+		{"-1m30s", -90 * time.Second, false},
+		{"-400ms", -400 * time.Millisecond, false},
+		{"about 2 hours", 2 * time.Hour, false},
+		{"01:02:03", time.Hour + 2*time.Minute + 3*time.Second, false},
+		{"-2d 01:02:03.250", -(2*24*time.Hour + time.Hour + 2*time.Minute + 3250*time.Millisecond), false},
+		{"1:60:00", 0, true},
+		{"01:02", 0, true},
+		{"01:02:-3", 0, true},
+		{"  3 Days  ", 3 * 24 * time.Hour, false},
+		{"", 0, true},
+		{"ago", 0, true},
+		{"5", 0, true},
+		{"5 fortnights", 0, true},
+		{"minutes", 0, true},
+		{"and 5 seconds", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseHumanDuration(tt.in)
+		if (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, ErrInvalidDuration)) {
+			t.Errorf("ParseHumanDuration(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseHumanDuration(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseHumanDurationRoundTrip(t *testing.T) {
+	for _, secs := range []int64{0, 1, 59, 61, 3600, 86399, 86400*9 + 3661, -3725} {
+		s := FormatSeconds(secs)
+		got, err := ParseHumanDuration(s)
+		if err != nil {
+			t.Errorf("ParseHumanDuration(%q) error = %v", s, err)
+			continue
+		}
+		if want := time.Duration(secs) * time.Second; got != want {
+			t.Errorf("ParseHumanDuration(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestParseHumanDurationStyles(t *testing.T) {
+	durations := []time.Duration{0, time.Second, 59 * time.Second, time.Hour + 2*time.Minute + 40*time.Second,
+		9*24*time.Hour + time.Hour + 5*time.Second, -90 * time.Second, -(26*time.Hour + 3*time.Second)}
+	subSecond := []time.Duration{350 * time.Millisecond, 2350 * time.Millisecond, -400 * time.Millisecond, -1250 * time.Millisecond}
+	for _, style := range []DurationStyle{DurationVerbose, DurationCompact, DurationClock, DurationRounded} {
+		for _, sub := range []bool{false, true} {
+			f := DurationFormat{Style: style, SubSecond: sub}
+			ds := durations
+			if sub {
+				ds = subSecond
+			}
+			for _, d := range ds {
+				s := f.Format(d)
+				got, err := ParseHumanDuration(s)
+				if err != nil {
+					t.Errorf("ParseHumanDuration(%q) error = %v", s, err)
+					continue
+				}
+				if style == DurationRounded {
+					// The rounded value is parsed, which formats without "about"
+					if want := strings.Replace(s, "about ", "", 1); f.Format(got) != want {
+						t.Errorf("ParseHumanDuration(%q) = %v, formats as %q, want %q", s, got, f.Format(got), want)
+					}
+				} else if got != d {
+					t.Errorf("ParseHumanDuration(%q) = %v, want %v", s, got, d)
+				}
+			}
+		}
+	}
+}
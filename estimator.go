@@ -0,0 +1,251 @@
+package progressio
+
+import (
+	"math"
+	"time"
+)
+
+// Estimator estimates the time remaining for a transfer, based on samples of
+// its progress. An Estimator instance keeps state about a single transfer, so
+// it can not be shared between several wrappers.
+type Estimator interface {
+	// Name returns the name of the estimator, which is reported in Progress.Estimator
+	Name() string
+	// Sample records the amount of bytes transferred at a certain time
+	Sample(t time.Time, transferred int64)
+	// Estimate returns the estimated time needed to transfer the remaining bytes,
+	// < 0 if it can't be estimated (yet), and the confidence in the estimate,
+	// between 0 (none) and 1 (certain).
+	Estimate(remaining int64) (time.Duration, float64)
+}
+
+// estimate converts a speed in bytes/sec into the time needed to transfer the
+// remaining bytes.
+func estimate(remaining int64, speed float64) time.Duration {
+	if remaining <= 0 {
+		return 0
+	}
+	if speed <= 0 || math.IsNaN(speed) || math.IsInf(speed, 0) {
+		return -1
+	}
+	return time.Duration(float64(remaining) / speed * float64(time.Second))
+}
+
+// clamp limits the confidence value to [0, 1]
+func clamp(c float64) float64 {
+	if math.IsNaN(c) || c < 0 {
+		return 0
+	}
+	if c > 1 {
+		return 1
+	}
+	return c
+}
+
+// AverageEstimator estimates the remaining time using the average speed over
+// the entire transfer. This is the default estimator.
+type AverageEstimator struct {
+	first, last sample
+	samples     int
+}
+
+// NewAverageEstimator creates a new AverageEstimator.
+func NewAverageEstimator() *AverageEstimator {
+	return &AverageEstimator{}
+}
+
+// Name returns "average"
+func (e *AverageEstimator) Name() string { return "average" }
+
+// Sample records the amount of bytes transferred at a certain time
+func (e *AverageEstimator) Sample(t time.Time, transferred int64) {
+	if e.samples == 0 {
+		e.first = sample{t, 0}
+	}
+	e.last = sample{t, transferred}
+	e.samples++
+}
+
+// Estimate returns the remaining time at the average speed since the first
+// sample. The confidence is the fraction of the transfer that is completed.
+func (e *AverageEstimator) Estimate(remaining int64) (time.Duration, float64) {
+	if remaining <= 0 {
+		return 0, 1
+	}
+	dt := e.last.t.Sub(e.first.t)
+	if dt <= 0 {
+		return -1, 0
+	}
+	speed := float64(e.last.n-e.first.n) / dt.Seconds()
+	return estimate(remaining, speed), clamp(float64(e.last.n) / float64(e.last.n+remaining))
+}
+
+// WindowEstimator estimates the remaining time using the average speed over
+// a sliding window of the most recent samples.
+type WindowEstimator struct {
+	window  time.Duration
+	samples []sample
+}
+
+// NewWindowEstimator creates a new WindowEstimator using the average speed over
+// the specified period.
+func NewWindowEstimator(window time.Duration) *WindowEstimator {
+	return &WindowEstimator{window: window}
+}
+
+// Name returns "window"
+func (e *WindowEstimator) Name() string { return "window" }
+
+// Sample records the amount of bytes transferred at a certain time. Samples
+// which fall out of the window are dropped, except the newest of those, which
+// serves as the start of the window.
+func (e *WindowEstimator) Sample(t time.Time, transferred int64) {
+	e.samples = append(e.samples, sample{t, transferred})
+	start := t.Add(-e.window)
+	i := 0
+	for i < len(e.samples)-1 && !e.samples[i+1].t.After(start) {
+		i++
+	}
+	e.samples = e.samples[i:]
+}
+
+// Estimate returns the remaining time at the average speed over the window.
+// The confidence is the part of the window covered by samples.
+func (e *WindowEstimator) Estimate(remaining int64) (time.Duration, float64) {
+	if remaining <= 0 {
+		return 0, 1
+	}
+	if len(e.samples) < 2 {
+		return -1, 0
+	}
+	first, last := e.samples[0], e.samples[len(e.samples)-1]
+	dt := last.t.Sub(first.t)
+	if dt <= 0 {
+		return -1, 0
+	}
+	speed := float64(last.n-first.n) / dt.Seconds()
+	return estimate(remaining, speed), clamp(float64(dt) / float64(e.window))
+}
+
+// EWMAEstimator estimates the remaining time using an exponentially weighted
+// moving average of the speed between consecutive samples, so recent samples
+// weigh more than older ones.
+type EWMAEstimator struct {
+	alpha    float64
+	last     sample
+	samples  int
+	speed    float64 // the moving average of the speed
+	variance float64 // the moving variance of the speed
+}
+
+// NewEWMAEstimator creates a new EWMAEstimator. Alpha is the weight of every new
+// sample, between 0 and 1: the higher, the faster the estimate reacts to changes.
+// Values outside (0, 1] are replaced by 0.1.
+func NewEWMAEstimator(alpha float64) *EWMAEstimator {
+	if alpha <= 0 || alpha > 1 {
+		alpha = 0.1
+	}
+	return &EWMAEstimator{alpha: alpha}
+}
+
+// Name returns "ewma"
+func (e *EWMAEstimator) Name() string { return "ewma" }
+
+// Sample records the amount of bytes transferred at a certain time
+func (e *EWMAEstimator) Sample(t time.Time, transferred int64) {
+	defer func() {
+		e.last = sample{t, transferred}
+		e.samples++
+	}()
+	if e.samples == 0 {
+		return
+	}
+	dt := t.Sub(e.last.t)
+	if dt <= 0 {
+		return
+	}
+	speed := float64(transferred-e.last.n) / dt.Seconds()
+	if e.samples == 1 {
+		e.speed = speed
+		return
+	}
+	diff := speed - e.speed
+	e.speed += e.alpha * diff
+	e.variance = (1 - e.alpha) * (e.variance + e.alpha*diff*diff)
+}
+
+// Estimate returns the remaining time at the moving average speed. The
+// confidence decreases as the speed varies more.
+func (e *EWMAEstimator) Estimate(remaining int64) (time.Duration, float64) {
+	if remaining <= 0 {
+		return 0, 1
+	}
+	if e.samples < 2 || e.speed <= 0 {
+		return -1, 0
+	}
+	cv := math.Sqrt(e.variance) / e.speed
+	return estimate(remaining, e.speed), clamp(1 / (1 + cv))
+}
+
+// RegressionEstimator estimates the remaining time using the speed obtained by
+// a least-squares linear regression over the most recent samples.
+type RegressionEstimator struct {
+	size    int
+	samples []sample
+}
+
+// NewRegressionEstimator creates a new RegressionEstimator using the specified
+// amount of most recent samples, at least 2.
+func NewRegressionEstimator(samples int) *RegressionEstimator {
+	if samples < 2 {
+		samples = 2
+	}
+	return &RegressionEstimator{size: samples}
+}
+
+// Name returns "regression"
+func (e *RegressionEstimator) Name() string { return "regression" }
+
+// Sample records the amount of bytes transferred at a certain time
+func (e *RegressionEstimator) Sample(t time.Time, transferred int64) {
+	e.samples = append(e.samples, sample{t, transferred})
+	if len(e.samples) > e.size {
+		e.samples = e.samples[len(e.samples)-e.size:]
+	}
+}
+
+// Estimate returns the remaining time at the speed given by the slope of the
+// regression line. The confidence is the coefficient of determination (R²)
+// of the regression.
+func (e *RegressionEstimator) Estimate(remaining int64) (time.Duration, float64) {
+	if remaining <= 0 {
+		return 0, 1
+	}
+	n := float64(len(e.samples))
+	if n < 2 {
+		return -1, 0
+	}
+	// Use times relative to the first sample to keep the numbers small
+	var sx, sy, sxx, sxy, syy float64
+	for _, s := range e.samples {
+		x := s.t.Sub(e.samples[0].t).Seconds()
+		y := float64(s.n)
+		sx += x
+		sy += y
+		sxx += x * x
+		sxy += x * y
+		syy += y * y
+	}
+	vx := n*sxx - sx*sx
+	if vx <= 0 {
+		return -1, 0
+	}
+	slope := (n*sxy - sx*sy) / vx
+	vy := n*syy - sy*sy
+	r2 := 1.0
+	if vy > 0 {
+		r := (n*sxy - sx*sy) / math.Sqrt(vx*vy)
+		r2 = r * r
+	}
+	return estimate(remaining, slope), clamp(r2)
+}
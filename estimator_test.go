@@ -0,0 +1,104 @@
+package progressio
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/bartmeuris/progressio/progressiotest"
+)
+
+func TestEstimators(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	// constant: 100 bytes/sec during 10 seconds
+	constant := []int64{0, 100, 200, 300, 400, 500, 600, 700, 800, 900, 1000}
+	// change: 10 bytes/sec during 5 seconds, then 1000 bytes/sec
+	change := []int64{0, 10, 20, 30, 40, 50, 1050, 2050, 3050, 4050, 5050}
+
+	tests := []struct {
+		name      string
+		estimator Estimator
+		samples   []int64
+		remaining int64
+		want      time.Duration
+		tolerance time.Duration
+		minConf   float64
+	}{
+		{"average constant", NewAverageEstimator(), constant, 1000, 10 * time.Second, 0, 0.5},
+		{"window constant", NewWindowEstimator(5 * time.Second), constant, 1000, 10 * time.Second, 0, 1},
+		{"ewma constant", NewEWMAEstimator(0.5), constant, 1000, 10 * time.Second, 0, 1},
+		{"regression constant", NewRegressionEstimator(5), constant, 1000, 10 * time.Second, 0, 1},
+		{"average change", NewAverageEstimator(), change, 1000, 1980 * time.Millisecond, 10 * time.Millisecond, 0},
+		{"window change", NewWindowEstimator(3 * time.Second), change, 1000, time.Second, 0, 1},
+		{"ewma change", NewEWMAEstimator(0.5), change, 1000, time.Second, 100 * time.Millisecond, 0},
+		{"regression change", NewRegressionEstimator(4), change, 1000, time.Second, 0, 1},
+		{"done", NewAverageEstimator(), constant, 0, 0, 0, 1},
+		{"no samples", NewRegressionEstimator(5), nil, 1000, -1, 0, 0},
+		{"one sample", NewWindowEstimator(time.Second), []int64{100}, 1000, -1, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, n := range tt.samples {
+				tt.estimator.Sample(start.Add(time.Duration(i)*time.Second), n)
+			}
+			got, conf := tt.estimator.Estimate(tt.remaining)
+			diff := got - tt.want
+			if diff < 0 {
+				diff = -diff
+			}
+			if diff > tt.tolerance {
+				t.Errorf("Estimate() got %v, want %v", got, tt.want)
+			}
+			if conf < tt.minConf || conf > 1 {
+				t.Errorf("Estimate() got confidence %.3f, want between %.3f and 1", conf, tt.minConf)
+			}
+		})
+	}
+}
+
+func TestWithEstimator(t *testing.T) {
+	clock := progressiotest.NewClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	var last Progress
+	w := NewProgressWriterWithOptions(ioutil.Discard, 1000,
+		WithCallback(func(p Progress) { last = p }),
+		WithClock(clock),
+		WithEstimator(NewWindowEstimator(time.Second)),
+	)
+	w.Write(make([]byte, 100))
+	clock.Advance(time.Second)
+	w.Write(make([]byte, 400))
+	if last.Estimator != "window" {
+		t.Errorf("Got estimator %q, expected %q", last.Estimator, "window")
+	}
+	if last.Remaining != 1250*time.Millisecond {
+		t.Errorf("Got remaining %v, expected %v", last.Remaining, 1250*time.Millisecond)
+	}
+}
+
+// countingEstimator counts the samples it received
+type countingEstimator struct {
+	AverageEstimator
+	samples int
+}
+
+func (e *countingEstimator) Sample(t time.Time, transferred int64) {
+	e.samples++
+	e.AverageEstimator.Sample(t, transferred)
+}
+
+func TestEstimatorSampleInterval(t *testing.T) {
+	clock := progressiotest.NewClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	e := &countingEstimator{}
+	// Nobody receives the updates, the estimator is still only sampled once
+	// per update interval
+	w := NewProgressWriterWithOptions(ioutil.Discard, -1, WithClock(clock), WithEstimator(e))
+	for i := 0; i < 1000; i++ {
+		w.Write(make([]byte, 10))
+	}
+	clock.Advance(UpdateFreq)
+	w.Write(make([]byte, 10))
+	if e.samples != 2 {
+		t.Errorf("Got %d samples, expected 2", e.samples)
+	}
+	w.Close()
+}
@@ -80,9 +80,9 @@ func ExampleWriter() {
 	fmt.Printf("Copy done\n")
 }
 
-// ExampleWriterSize is an example of using the progressio package with an
+// Example_writerSize is an example of using the progressio package with an
 // io.Writer while knowing the expected amount of bytes to be processed.
-func ExampleWriterSize() {
+func Example_writerSize() {
 	r := getReader()
 	w, ch := NewProgressWriter(getWriter(), -1)
 
@@ -104,9 +104,9 @@ func ExampleReader() {
 	fmt.Printf("Copy done\n")
 }
 
-// ExampleReaderSize is an example of using the progressio package with an
+// Example_readerSize is an example of using the progressio package with an
 // io.Reader while knowing the expected amount of bytes to be processed.
-func ExampleReaderSize() {
+func Example_readerSize() {
 	r, ch := NewProgressReader(getReader(), bufSize)
 	w := getWriter()
 
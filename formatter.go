@@ -0,0 +1,114 @@
+package progressio
+
+import (
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Formatter formats Progress objects using a text/template. The fields and
+// methods of the Progress object can be used in the template, together with
+// these functions:
+//
+//	bytes N      N bytes formatted using the size system, e.g. {{bytes .Transferred}}
+//	speed N      N bytes/sec formatted using the size system, e.g. {{speed .Speed}}
+//	eta D        the time remaining D, "unknown" if < 0, e.g. {{eta .Remaining}}
+//	duration D   the duration D, e.g. {{duration .Elapsed}}
+//	bar W        a progress bar W characters wide, e.g. {{bar 30}}
+//
+// The durations are formatted using the DurationFormat of the Formatter.
+// A single Formatter can be used from several goroutines.
+type Formatter struct {
+	SizeSystem SizeSystem     // The size system used by bytes and speed, IEC by default
+	Long       bool           // Use the long unit names instead of the short ones
+	Durations  DurationFormat // The format used by eta and duration, verbose by default
+
+	tmpl *template.Template
+}
+
+// The functions available to the templates, the Progress specific ones are
+// replaced when executing them.
+var formatterFuncs = template.FuncMap{
+	"bytes":    func(int64) string { return "" },
+	"speed":    func(int64) string { return "" },
+	"eta":      func(time.Duration) string { return "" },
+	"duration": func(time.Duration) string { return "" },
+	"bar":      func(int) string { return "" },
+}
+
+// NewFormatter creates a new Formatter from the template text.
+func NewFormatter(text string) (*Formatter, error) {
+	tmpl, err := template.New("progress").Funcs(formatterFuncs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return &Formatter{
+		SizeSystem: IEC,
+		tmpl:       tmpl,
+	}, nil
+}
+
+// Format formats the progress using the template of the Formatter.
+func (f *Formatter) Format(p Progress) (string, error) {
+	tmpl, err := f.tmpl.Clone()
+	if err != nil {
+		return "", err
+	}
+	tmpl.Funcs(template.FuncMap{
+		"bytes": func(n int64) string {
+			return FormatSize(f.SizeSystem, n, !f.Long)
+		},
+		"speed": func(n int64) string {
+			if n < 0 {
+				return "unknown"
+			}
+			return FormatSize(f.SizeSystem, n, !f.Long) + "/s"
+		},
+		"eta": func(d time.Duration) string {
+			if d < 0 {
+				return "unknown"
+			}
+			return f.Durations.Format(d)
+		},
+		"duration": f.Durations.Format,
+		"bar": func(width int) string {
+			if p.TotalSize <= 0 {
+				return FormatBar(-1, width)
+			}
+			return FormatBar(p.Percent, width)
+		},
+	})
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, &p); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// Format formats the progress using the template text, see Formatter for the
+// functions available.
+func (p *Progress) Format(text string) (string, error) {
+	f, err := NewFormatter(text)
+	if err != nil {
+		return "", err
+	}
+	return f.Format(*p)
+}
+
+// FormatBar returns a progress bar of width characters, including the brackets,
+// filled up to the percentage. A negative percentage means the progress is
+// unknown, which gives an empty bar. The bar is "" if width is less than 3.
+func FormatBar(percent float64, width int) string {
+	inner := width - 2
+	if inner < 1 {
+		return ""
+	}
+	if percent < 0 {
+		return "[" + strings.Repeat(" ", inner) + "]"
+	}
+	done := int(float64(inner) * percent / 100)
+	if done >= inner {
+		return "[" + strings.Repeat("=", inner) + "]"
+	}
+	return "[" + strings.Repeat("=", done) + ">" + strings.Repeat(" ", inner-done-1) + "]"
+}
@@ -0,0 +1,101 @@
+package progressio
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatter(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	p := Progress{
+		Transferred: 5 * MegaByte,
+		TotalSize:   10 * MegaByte,
+		Percent:     50,
+		Speed:       MegaByte,
+		SpeedAvg:    -1,
+		Remaining:   5 * time.Second,
+		StartTime:   start,
+		StopTime:    start.Add(90 * time.Second),
+	}
+	tests := []struct {
+		name   string
+		text   string
+		ss     SizeSystem
+		long   bool
+		expect string
+	}{
+		{"fields", "{{.Percent}}% {{.Transferred}}/{{.TotalSize}}", IEC, false, "50% 5000000/10000000"},
+		{"iec", "{{bytes .Transferred}} at {{speed .Speed}}", IEC, false, "4.77MiB at 976.56KiB/s"},
+		{"metric", "{{bytes .Transferred}} at {{speed .Speed}}", Metric, false, "5.00MB at 1.00MB/s"},
+		{"long", "{{bytes .Transferred}}", Metric, true, "5.00 megabyte"},
+		{"unknown speed", "{{speed .SpeedAvg}}", IEC, false, "unknown"},
+		{"eta", "ETA {{eta .Remaining}}, took {{duration .Elapsed}}", IEC, false, "ETA 5 seconds, took 1 minute, 30 seconds"},
+		{"bar", "{{bar 12}} {{printf \"%.1f\" .Percent}}%", IEC, false, "[=====>    ] 50.0%"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewFormatter(tt.text)
+			if err != nil {
+				t.Fatalf("NewFormatter() error = %v", err)
+			}
+			f.SizeSystem = tt.ss
+			f.Long = tt.long
+			got, err := f.Format(p)
+			if err != nil {
+				t.Fatalf("Format() error = %v", err)
+			}
+			if got != tt.expect {
+				t.Errorf("Format() got %q, want %q", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestProgressFormat(t *testing.T) {
+	p := Progress{TotalSize: -1, Transferred: KibiByte, Remaining: -1}
+	got, err := p.Format("{{bar 6}} {{bytes .Transferred}} ETA {{eta .Remaining}}")
+	if err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+	if expect := "[    ] 1.00KiB ETA unknown"; got != expect {
+		t.Errorf("Format() got %q, want %q", got, expect)
+	}
+	if _, err := p.Format("{{bogus}}"); err == nil {
+		t.Errorf("Format() expected an error for an unknown function")
+	}
+}
+
+func TestFormatterDurations(t *testing.T) {
+	f, err := NewFormatter("{{eta .Remaining}}")
+	if err != nil {
+		t.Fatalf("NewFormatter() error = %v", err)
+	}
+	f.Durations = DurationFormat{Style: DurationClock}
+	got, err := f.Format(Progress{Remaining: 3723 * time.Second})
+	if err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+	if got != "01:02:03" {
+		t.Errorf("Format() got %q, want %q", got, "01:02:03")
+	}
+}
+
+func TestFormatBar(t *testing.T) {
+	tests := []struct {
+		percent float64
+		width   int
+		want    string
+	}{
+		{0, 12, "[>         ]"},
+		{50, 12, "[=====>    ]"},
+		{100, 12, "[==========]"},
+		{150, 6, "[====]"},
+		{-1, 6, "[    ]"},
+		{50, 2, ""},
+	}
+	for _, tt := range tests {
+		if got := FormatBar(tt.percent, tt.width); got != tt.want {
+			t.Errorf("FormatBar(%v, %d) got %q, want %q", tt.percent, tt.width, got, tt.want)
+		}
+	}
+}
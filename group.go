@@ -0,0 +1,154 @@
+package progressio
+
+import "io"
+
+// ProgressGroup aggregates the progress of several ProgressReader and
+// ProgressWriter objects into a single Progress stream. The Transferred and
+// TotalSize of the aggregated Progress are the sums of those of the members,
+// and the speed, average speed and remaining time are calculated over the
+// combined transfer. If the size of any member is unknown, the total size is
+// unknown too.
+//
+// A ProgressGroup is not completed when all members are, since members can be
+// added at any time: it has to be closed to send the final update and close
+// the channel.
+type ProgressGroup struct {
+	*ioProgress
+	members []*ioProgress
+	last    map[*ioProgress]Progress
+	known   int64 // The sum of the known member sizes
+	unknown int   // The amount of members with an unknown size
+}
+
+// NewProgressGroup creates a new, empty ProgressGroup and the channel over which
+// the aggregated progress is sent.
+func NewProgressGroup() (*ProgressGroup, <-chan Progress) {
+	ch := make(chan Progress)
+	ret := &ProgressGroup{
+		ioProgress: mkIoProgress(0, WithChannel(ch), WithGuaranteedFinal()),
+		last:       make(map[*ioProgress]Progress),
+	}
+	ret.keepOpen = true
+	return ret, ch
+}
+
+// NewReader creates a new ProgressReader object which is a member of the group.
+// Unless options to receive it are specified, its progress is only reported
+// through the group, and can be consulted using the Members function. Specify
+// a size <= 0 if you don't know the size.
+func (g *ProgressGroup) NewReader(r io.Reader, size int64, opts ...Option) *ProgressReader {
+	ret := NewProgressReaderWithOptions(r, size, opts...)
+	if ret == nil {
+		return nil
+	}
+	g.adopt(ret.ioProgress)
+	return ret
+}
+
+// NewWriter creates a new ProgressWriter object which is a member of the group.
+// Unless options to receive it are specified, its progress is only reported
+// through the group, and can be consulted using the Members function. Specify
+// a size <= 0 if you don't know the size.
+func (g *ProgressGroup) NewWriter(w io.Writer, size int64, opts ...Option) *ProgressWriter {
+	ret := NewProgressWriterWithOptions(w, size, opts...)
+	if ret == nil {
+		return nil
+	}
+	g.adopt(ret.ioProgress)
+	return ret
+}
+
+// AddReader adds an existing ProgressReader to the group. It keeps sending
+// updates over its own channel too. Data it already transferred is counted
+// by the group.
+func (g *ProgressGroup) AddReader(r *ProgressReader) {
+	g.adopt(r.ioProgress)
+}
+
+// AddWriter adds an existing ProgressWriter to the group. It keeps sending
+// updates over its own channel too. Data it already transferred is counted
+// by the group.
+func (g *ProgressGroup) AddWriter(w *ProgressWriter) {
+	g.adopt(w.ioProgress)
+}
+
+// Members returns the last known progress of every member of the group, in
+// the order they were added.
+func (g *ProgressGroup) Members() []Progress {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	ret := make([]Progress, len(g.members))
+	for i, m := range g.members {
+		ret[i] = g.last[m]
+	}
+	return ret
+}
+
+// memberList returns a copy of the members, so they can be used without
+// holding the lock of the group.
+func (g *ProgressGroup) memberList() []*ioProgress {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]*ioProgress(nil), g.members...)
+}
+
+// Close stops the group: the final aggregated update is sent and the channel
+// is closed. The members are not closed, that remains the caller's
+// responsibility.
+func (g *ProgressGroup) Close() error {
+	g.stopProgress(nil)
+	return nil
+}
+
+// adopt registers the member with the group. Locks are always taken in the
+// member -> group order, as is the case when a member reports to the group.
+func (g *ProgressGroup) adopt(m *ioProgress) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.group != nil {
+		return
+	}
+	m.group = g
+
+	g.mu.Lock()
+	g.members = append(g.members, m)
+	g.last[m] = Progress{
+		Transferred: m.progress,
+		TotalSize:   m.size,
+		StartTime:   m.startTime,
+	}
+	if m.size > 0 {
+		g.known += m.size
+	} else {
+		g.unknown++
+	}
+	if g.unknown > 0 {
+		g.size = -1
+	} else {
+		g.size = g.known
+	}
+	g.offset += m.offset
+	g.release(g.update(m.progress, false))
+}
+
+// memberWritten adds the bytes written by a member to the group's progress.
+func (g *ProgressGroup) memberWritten(written int64) {
+	g.mu.Lock()
+	g.release(g.update(written, false))
+}
+
+// memberMoved moves the progress of the group after a member seeked, without
+// sending an update. The offset moves along, like the one of the member.
+func (g *ProgressGroup) memberMoved(delta int64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.progress += delta
+	g.offset += delta
+}
+
+// memberProgress stores the last progress sent by a member.
+func (g *ProgressGroup) memberProgress(m *ioProgress, p Progress) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.last[m] = p
+}
@@ -0,0 +1,79 @@
+package progressio
+
+import (
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestProgressGroup(t *testing.T) {
+	g, ch := NewProgressGroup()
+	done := make(chan Progress)
+	go func() {
+		last := Progress{}
+		for p := range ch {
+			last = p
+		}
+		done <- last
+	}()
+
+	r1 := g.NewReader(strings.NewReader(strings.Repeat("a", 100)), 100)
+	r2 := g.NewReader(strings.NewReader(strings.Repeat("b", 300)), 300)
+	w, wch := NewProgressWriter(ioutil.Discard, 50)
+	go func() {
+		for range wch {
+		}
+	}()
+	w.Write(make([]byte, 10))
+	g.AddWriter(w)
+
+	io.Copy(ioutil.Discard, r1)
+	io.Copy(ioutil.Discard, r2)
+	w.Write(make([]byte, 40))
+	r1.Close()
+	r2.Close()
+	w.Close()
+
+	members := g.Members()
+	if len(members) != 3 {
+		t.Fatalf("Members: got %d members, expected 3", len(members))
+	}
+	for i, expect := range []int64{100, 300, 50} {
+		if members[i].Transferred != expect || members[i].TotalSize != expect {
+			t.Errorf("Member %d: got %d/%d, expected %d/%d", i, members[i].Transferred, members[i].TotalSize, expect, expect)
+		}
+		if members[i].StopTime.IsZero() {
+			t.Errorf("Member %d: StopTime not set", i)
+		}
+	}
+
+	g.Close()
+	last := <-done
+	if last.Transferred != 450 || last.TotalSize != 450 {
+		t.Errorf("Group: got %d/%d, expected 450/450", last.Transferred, last.TotalSize)
+	}
+	if last.Percent != 100 {
+		t.Errorf("Group: got Percent %.2f, expected 100", last.Percent)
+	}
+}
+
+func TestProgressGroupUnknownSize(t *testing.T) {
+	g, ch := NewProgressGroup()
+	go func() {
+		for range ch {
+		}
+	}()
+	g.NewWriter(ioutil.Discard, 100).Write(make([]byte, 10))
+	g.NewWriter(ioutil.Discard, -1).Write(make([]byte, 20))
+	g.mu.Lock()
+	size, progress := g.size, g.progress
+	g.mu.Unlock()
+	if size > 0 {
+		t.Errorf("Group: got size %d, expected unknown size", size)
+	}
+	if progress != 30 {
+		t.Errorf("Group: got progress %d, expected 30", progress)
+	}
+	g.Close()
+}
@@ -0,0 +1,178 @@
+package progressio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReaderSeek(t *testing.T) {
+	var last Progress
+	r := NewProgressReaderWithOptions(strings.NewReader("0123456789"), 10,
+		WithUpdateInterval(0),
+		WithCallback(func(p Progress) { last = p }))
+	defer r.Close()
+
+	b := make([]byte, 4)
+	r.Read(b)
+	if last.Transferred != 4 {
+		t.Fatalf("Transferred %d after reading, want 4", last.Transferred)
+	}
+	// Seeking to the end to find the size does not complete the transfer
+	if size, err := r.Seek(0, io.SeekEnd); err != nil || size != 10 {
+		t.Fatalf("Seek(0, io.SeekEnd) = %d, %v", size, err)
+	}
+	if _, err := r.Seek(2, io.SeekStart); err != nil {
+		t.Fatalf("Seek(2, io.SeekStart) failed: %v", err)
+	}
+	r.Read(b)
+	if last.Transferred != 6 || last.State != StateRunning {
+		t.Errorf("After seeking back: transferred %d, state %v, want 6, running", last.Transferred, last.State)
+	}
+}
+
+func TestReaderAt(t *testing.T) {
+	var last Progress
+	r := NewProgressReaderWithOptions(strings.NewReader("0123456789"), 10,
+		WithUpdateInterval(0),
+		WithCallback(func(p Progress) { last = p }))
+	defer r.Close()
+
+	b := make([]byte, 4)
+	for _, off := range []int64{6, 0, 2, 6} {
+		if _, err := r.ReadAt(b, off); err != nil {
+			t.Fatalf("ReadAt(%d) failed: %v", off, err)
+		}
+	}
+	if last.Transferred != 10 || last.State != StateDone {
+		t.Errorf("Got %d unique bytes, state %v, want 10, done", last.Transferred, last.State)
+	}
+}
+
+func TestReaderMixedAccess(t *testing.T) {
+	var last Progress
+	r := NewProgressReaderWithOptions(strings.NewReader("0123456789"), 10,
+		WithUpdateInterval(0),
+		WithCallback(func(p Progress) { last = p }))
+	defer r.Close()
+
+	b := make([]byte, 5)
+	steps := []struct {
+		name string
+		do   func()
+		want int64
+	}{
+		{"Read 0-5", func() { r.Read(b) }, 5},
+		{"ReadAt 0-5", func() { r.ReadAt(b, 0) }, 5},
+		{"ReadAt 3-8", func() { r.ReadAt(b, 3) }, 8},
+		{"Seek 0, Read 0-5", func() { r.Seek(0, io.SeekStart); r.Read(b) }, 8},
+		{"Read 5-10", func() { r.Read(b) }, 10},
+	}
+	for _, s := range steps {
+		s.do()
+		if last.Transferred != s.want {
+			t.Errorf("%s: transferred %d, want %d", s.name, last.Transferred, s.want)
+		}
+	}
+	if last.State != StateDone {
+		t.Errorf("Got state %v after reading every byte, want done", last.State)
+	}
+}
+
+func TestWrapperFastPaths(t *testing.T) {
+	dir, err := ioutil.TempDir("", "progressio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "src")
+	data := bytes.Repeat([]byte("x"), 3*copyChunkSize+10)
+	if err := ioutil.WriteFile(src, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// io.Copy between files uses the ReadFrom of the destination file
+	r, ch, err := NewProgressFileReader(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updates := make(chan int)
+	go func() {
+		n := 0
+		for range ch {
+			n++
+		}
+		updates <- n
+	}()
+	dst, err := os.Create(filepath.Join(dir, "dst"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+	w := NewProgressWriterWithOptions(dst, int64(len(data)))
+	if n, err := io.Copy(w, r); err != nil || n != int64(len(data)) {
+		t.Fatalf("io.Copy = %d, %v", n, err)
+	}
+	if err := r.Wait(); err != nil {
+		t.Errorf("Reader failed: %v", err)
+	}
+	if err := w.Wait(); err != nil {
+		t.Errorf("Writer failed: %v", err)
+	}
+	if n := <-updates; n < 1 {
+		t.Errorf("Got no updates")
+	}
+	if got, _ := ioutil.ReadFile(dst.Name()); !bytes.Equal(got, data) {
+		t.Errorf("Copied %d bytes, want %d", len(got), len(data))
+	}
+}
+
+func TestWriterReadFrom(t *testing.T) {
+	var last Progress
+	var buf bytes.Buffer
+	w := NewProgressWriterWithOptions(&buf, -1, WithCallback(func(p Progress) { last = p }))
+	if n, err := io.Copy(w, struct{ io.Reader }{strings.NewReader("some data")}); err != nil || n != 9 {
+		t.Fatalf("io.Copy = %d, %v", n, err)
+	}
+	w.Close()
+	if last.Transferred != 9 || buf.String() != "some data" {
+		t.Errorf("Transferred %d, wrote %q", last.Transferred, buf.String())
+	}
+}
+
+func TestUnsupportedInterfaces(t *testing.T) {
+	r := NewProgressReaderWithOptions(struct{ io.Reader }{strings.NewReader("data")}, 4)
+	defer r.Close()
+	if _, err := r.Seek(0, io.SeekStart); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("Seek error = %v, want errors.ErrUnsupported", err)
+	}
+	if _, err := r.ReadAt(make([]byte, 1), 0); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("ReadAt error = %v, want errors.ErrUnsupported", err)
+	}
+	if _, ok := r.Reader().(io.Seeker); ok {
+		t.Errorf("Reader() implements io.Seeker")
+	}
+	if _, ok := r.Reader().(io.ReaderAt); ok {
+		t.Errorf("Reader() implements io.ReaderAt")
+	}
+
+	sr := NewProgressReaderWithOptions(strings.NewReader("data"), 4)
+	defer sr.Close()
+	if _, ok := sr.Reader().(io.ReadSeeker); !ok {
+		t.Errorf("Reader() does not implement io.Seeker")
+	}
+	if _, ok := sr.Reader().(io.ReaderAt); !ok {
+		t.Errorf("Reader() does not implement io.ReaderAt")
+	}
+
+	w := NewProgressWriterWithOptions(&bytes.Buffer{}, -1)
+	defer w.Close()
+	if _, ok := w.Writer().(io.Seeker); ok {
+		t.Errorf("Writer() implements io.Seeker")
+	}
+}
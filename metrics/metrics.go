@@ -0,0 +1,272 @@
+/*
+Package metrics exports metrics of the transfers going through progressio
+wrappers in the Prometheus text exposition format, without depending on the
+Prometheus client library.
+
+A Collector keeps, for every set of labels, the amount of bytes transferred,
+the transfers in progress and their current throughput, the transfers
+completed, and a histogram of their durations. Wrappers report to it through
+the Track option, and the Collector serves the metrics as an http.Handler:
+
+	c := metrics.NewCollector()
+	http.Handle("/metrics", c)
+
+	r := c.NewReader(myreader, size, metrics.Labels{"direction": "download"})
+	defer r.Close()
+	io.Copy(mywriter, r)
+
+The following metrics are exported, with "progressio" as the default namespace:
+
+	progressio_bytes_total                   counter    bytes transferred
+	progressio_active_transfers              gauge      transfers in progress
+	progressio_throughput_bytes_per_second   gauge      current speed of the transfers in progress
+	progressio_transfers_total               counter    transfers completed, by "state" (done or failed)
+	progressio_transfer_duration_seconds     histogram  duration of the transfers completed
+*/
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bartmeuris/progressio"
+)
+
+// DefaultNamespace is the prefix of the metric names if no Namespace is set
+const DefaultNamespace = "progressio"
+
+// DefaultBuckets are the upper bounds in seconds of the buckets of the
+// duration histogram if no Buckets are set
+var DefaultBuckets = []float64{0.1, 0.5, 1, 5, 10, 30, 60, 300, 900, 3600}
+
+// Labels are the labels of the metrics of a transfer, e.g. the direction or
+// the name of the remote service. The label names must be valid Prometheus
+// label names, "state" and "le" are reserved.
+type Labels map[string]string
+
+var labelName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Collector collects the metrics of the transfers tracked, per set of labels.
+// It is safe for concurrent use. The Buckets only apply to the sets of labels
+// tracked after changing them.
+type Collector struct {
+	Namespace string    // Prefix of the metric names, DefaultNamespace if empty
+	Buckets   []float64 // Upper bounds of the duration histogram buckets, DefaultBuckets if nil
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+// series holds the metrics of one set of labels
+type series struct {
+	labels    string // the formatted labels, without braces
+	bytes     int64
+	active    int64
+	speed     int64
+	completed map[progressio.State]int64
+	bounds    []float64 // the upper bounds of the buckets
+	buckets   []int64   // the amount of durations per bucket, not cumulative
+	sum       float64
+	count     int64
+}
+
+// NewCollector creates a new Collector using the default namespace and buckets.
+func NewCollector() *Collector {
+	return &Collector{}
+}
+
+// Track returns an option which reports the progress of a wrapper to the
+// collector, under the labels. The option keeps the state of a single wrapper,
+// so every wrapper needs its own. It panics if a label name is invalid.
+func (c *Collector) Track(labels Labels) progressio.Option {
+	s := c.get(labels)
+	var started bool
+	var last, speed int64
+	return progressio.WithCallback(func(p progressio.Progress) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if !started {
+			started = true
+			s.active++
+		}
+		// Bytes transferred again after seeking back are counted again
+		if delta := p.Session - last; delta > 0 {
+			s.bytes += delta
+		}
+		last = p.Session
+		cur := p.Speed
+		if cur < 0 || !p.StopTime.IsZero() {
+			cur = 0
+		}
+		s.speed += cur - speed
+		speed = cur
+		if !p.StopTime.IsZero() {
+			s.active--
+			s.completed[p.State]++
+			s.observe(p.Elapsed().Seconds())
+		}
+	})
+}
+
+// NewReader creates a new ProgressReader tracked by the collector under the
+// labels, see progressio.NewProgressReaderWithOptions.
+func (c *Collector) NewReader(r io.Reader, size int64, labels Labels, opts ...progressio.Option) *progressio.ProgressReader {
+	return progressio.NewProgressReaderWithOptions(r, size, append(opts[:len(opts):len(opts)], c.Track(labels))...)
+}
+
+// NewWriter creates a new ProgressWriter tracked by the collector under the
+// labels, see progressio.NewProgressWriterWithOptions.
+func (c *Collector) NewWriter(w io.Writer, size int64, labels Labels, opts ...progressio.Option) *progressio.ProgressWriter {
+	return progressio.NewProgressWriterWithOptions(w, size, append(opts[:len(opts):len(opts)], c.Track(labels))...)
+}
+
+// ServeHTTP serves the metrics in the Prometheus text exposition format.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format to w.
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+	// Render the metrics first, so the lock is not held while writing to w
+	var buf bytes.Buffer
+	ns := c.namespace()
+	c.mu.Lock()
+	all := make([]*series, 0, len(c.series))
+	for _, s := range c.series {
+		all = append(all, s)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].labels < all[j].labels })
+
+	header := func(name, typ, help string) {
+		fmt.Fprintf(&buf, "# HELP %s_%s %s\n# TYPE %s_%s %s\n", ns, name, help, ns, name, typ)
+	}
+	header("bytes_total", "counter", "Bytes transferred.")
+	for _, s := range all {
+		fmt.Fprintf(&buf, "%s_bytes_total%s %d\n", ns, braces(s.labels), s.bytes)
+	}
+	header("active_transfers", "gauge", "Transfers in progress.")
+	for _, s := range all {
+		fmt.Fprintf(&buf, "%s_active_transfers%s %d\n", ns, braces(s.labels), s.active)
+	}
+	header("throughput_bytes_per_second", "gauge", "Current speed of the transfers in progress.")
+	for _, s := range all {
+		fmt.Fprintf(&buf, "%s_throughput_bytes_per_second%s %d\n", ns, braces(s.labels), s.speed)
+	}
+	header("transfers_total", "counter", "Transfers completed.")
+	for _, s := range all {
+		for _, st := range []progressio.State{progressio.StateDone, progressio.StateFailed} {
+			fmt.Fprintf(&buf, "%s_transfers_total%s %d\n", ns, braces(join(s.labels, "state", st.String())), s.completed[st])
+		}
+	}
+	header("transfer_duration_seconds", "histogram", "Duration of the transfers completed.")
+	for _, s := range all {
+		var cumulative int64
+		for i, le := range s.bounds {
+			cumulative += s.buckets[i]
+			fmt.Fprintf(&buf, "%s_transfer_duration_seconds_bucket%s %d\n", ns, braces(join(s.labels, "le", formatFloat(le))), cumulative)
+		}
+		fmt.Fprintf(&buf, "%s_transfer_duration_seconds_bucket%s %d\n", ns, braces(join(s.labels, "le", "+Inf")), s.count)
+		fmt.Fprintf(&buf, "%s_transfer_duration_seconds_sum%s %s\n", ns, braces(s.labels), formatFloat(s.sum))
+		fmt.Fprintf(&buf, "%s_transfer_duration_seconds_count%s %d\n", ns, braces(s.labels), s.count)
+	}
+	c.mu.Unlock()
+	return buf.WriteTo(w)
+}
+
+// get returns the series of the labels, creating it if needed.
+func (c *Collector) get(labels Labels) *series {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		if !labelName.MatchString(name) || name == "state" || name == "le" {
+			panic(fmt.Sprintf("metrics: invalid label name %q", name))
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = pair(name, labels[name])
+	}
+	key := strings.Join(pairs, ",")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.series == nil {
+		c.series = make(map[string]*series)
+	}
+	s, ok := c.series[key]
+	if !ok {
+		s = &series{
+			labels:    key,
+			completed: make(map[progressio.State]int64),
+			bounds:    c.buckets(),
+			buckets:   make([]int64, len(c.buckets())),
+		}
+		c.series[key] = s
+	}
+	return s
+}
+
+// observe adds the duration to the histogram of the series, c.mu must be held.
+func (s *series) observe(seconds float64) {
+	for i, le := range s.bounds {
+		if seconds <= le {
+			s.buckets[i]++
+			break
+		}
+	}
+	s.sum += seconds
+	s.count++
+}
+
+func (c *Collector) namespace() string {
+	if c.Namespace == "" {
+		return DefaultNamespace
+	}
+	return c.Namespace
+}
+
+func (c *Collector) buckets() []float64 {
+	if c.Buckets == nil {
+		return DefaultBuckets
+	}
+	return c.Buckets
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// pair formats a label pair, escaping the value
+func pair(name, value string) string {
+	return name + `="` + labelEscaper.Replace(value) + `"`
+}
+
+// join adds a label pair to the formatted labels
+func join(labels, name, value string) string {
+	if labels == "" {
+		return pair(name, value)
+	}
+	return labels + "," + pair(name, value)
+}
+
+func braces(labels string) string {
+	if labels == "" {
+		return ""
+	}
+	return "{" + labels + "}"
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bartmeuris/progressio"
+	"github.com/bartmeuris/progressio/progressiotest"
+)
+
+// failingReader fails after returning some data
+type failingReader struct{ n int }
+
+func (f *failingReader) Read(b []byte) (int, error) {
+	if f.n <= 0 {
+		return 0, errors.New("broken")
+	}
+	f.n -= len(b)
+	return len(b), nil
+}
+
+func TestCollector(t *testing.T) {
+	clock := progressiotest.NewClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	c := NewCollector()
+	c.Buckets = []float64{1, 10}
+	download := Labels{"direction": "download", "service": `a "quoted" name`}
+
+	// A completed download of 2 seconds
+	r := c.NewReader(strings.NewReader(strings.Repeat("x", 1000)), 1000, download,
+		progressio.WithClock(clock), progressio.WithUpdateInterval(0))
+	b := make([]byte, 500)
+	r.Read(b)
+	clock.Advance(2 * time.Second)
+	io.Copy(ioutil.Discard, r)
+	r.Close()
+
+	// A failed download of 20 seconds
+	r = c.NewReader(&failingReader{100}, -1, download, progressio.WithClock(clock))
+	r.Read(make([]byte, 100))
+	clock.Advance(20 * time.Second)
+	r.Read(b)
+
+	// An upload in progress
+	w := c.NewWriter(ioutil.Discard, -1, Labels{"direction": "upload"},
+		progressio.WithClock(clock), progressio.WithUpdateInterval(0))
+	w.Write(make([]byte, 100))
+	clock.Advance(time.Second)
+	w.Write(make([]byte, 300))
+	defer w.Close()
+
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type %q", ct)
+	}
+	want := `# HELP progressio_bytes_total Bytes transferred.
+# TYPE progressio_bytes_total counter
+progressio_bytes_total{direction="download",service="a \"quoted\" name"} 1100
+progressio_bytes_total{direction="upload"} 400
+# HELP progressio_active_transfers Transfers in progress.
+# TYPE progressio_active_transfers gauge
+progressio_active_transfers{direction="download",service="a \"quoted\" name"} 0
+progressio_active_transfers{direction="upload"} 1
+# HELP progressio_throughput_bytes_per_second Current speed of the transfers in progress.
+# TYPE progressio_throughput_bytes_per_second gauge
+progressio_throughput_bytes_per_second{direction="download",service="a \"quoted\" name"} 0
+progressio_throughput_bytes_per_second{direction="upload"} 300
+# HELP progressio_transfers_total Transfers completed.
+# TYPE progressio_transfers_total counter
+progressio_transfers_total{direction="download",service="a \"quoted\" name",state="done"} 1
+progressio_transfers_total{direction="download",service="a \"quoted\" name",state="failed"} 1
+progressio_transfers_total{direction="upload",state="done"} 0
+progressio_transfers_total{direction="upload",state="failed"} 0
+# HELP progressio_transfer_duration_seconds Duration of the transfers completed.
+# TYPE progressio_transfer_duration_seconds histogram
+progressio_transfer_duration_seconds_bucket{direction="download",service="a \"quoted\" name",le="1"} 0
+progressio_transfer_duration_seconds_bucket{direction="download",service="a \"quoted\" name",le="10"} 1
+progressio_transfer_duration_seconds_bucket{direction="download",service="a \"quoted\" name",le="+Inf"} 2
+progressio_transfer_duration_seconds_sum{direction="download",service="a \"quoted\" name"} 22
+progressio_transfer_duration_seconds_count{direction="download",service="a \"quoted\" name"} 2
+progressio_transfer_duration_seconds_bucket{direction="upload",le="1"} 0
+progressio_transfer_duration_seconds_bucket{direction="upload",le="10"} 0
+progressio_transfer_duration_seconds_bucket{direction="upload",le="+Inf"} 0
+progressio_transfer_duration_seconds_sum{direction="upload"} 0
+progressio_transfer_duration_seconds_count{direction="upload"} 0
+`
+	if got := rec.Body.String(); got != want {
+		t.Errorf("Got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestInvalidLabel(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Track with an invalid label name did not panic")
+		}
+	}()
+	NewCollector().Track(Labels{"not-valid": "x"})
+}
@@ -0,0 +1,149 @@
+package progressio
+
+import (
+	"context"
+	"time"
+)
+
+// Option configures a ProgressReader or ProgressWriter created with
+// NewProgressReaderWithOptions or NewProgressWriterWithOptions.
+type Option func(*ioProgress)
+
+// WithCallback registers a callback which is called with every update. The
+// callbacks are called synchronously from Read/Write and Close, so they should
+// return quickly and must not call the methods of the wrapper itself.
+// Contrary to the channels, callbacks never miss an update.
+func WithCallback(fn func(Progress)) Option {
+	return func(p *ioProgress) {
+		if fn != nil {
+			p.callbacks = append(p.callbacks, fn)
+		}
+	}
+}
+
+// WithChannel registers a channel to receive the updates over. The buffer size
+// of the channel determines how many updates can be queued: updates are only
+// sent when they would not block, and are dropped otherwise. The channel is
+// closed after the final update. Several channels can be registered, every
+// channel receives the updates independently of the others.
+func WithChannel(ch chan<- Progress) Option {
+	return func(p *ioProgress) {
+		if ch != nil {
+			p.subs = append(p.subs, ch)
+		}
+	}
+}
+
+// WithGuaranteedFinal makes sure the final update is delivered to all the
+// registered channels, by blocking until they received it. Without it, the
+// final update is dropped for channels that are not ready to receive it.
+func WithGuaranteedFinal() Option {
+	return func(p *ioProgress) {
+		p.guarantee = true
+	}
+}
+
+// WithKeepOpen keeps the transfer open until the wrapper is closed: reaching
+// EOF or the size does not finish it, e.g. when the data can be transferred
+// again after seeking back.
+func WithKeepOpen() Option {
+	return func(p *ioProgress) {
+		p.keepOpen = true
+	}
+}
+
+// WithUpdateInterval sets the minimum time between two updates, UpdateFreq by
+// default. The final update is always sent.
+func WithUpdateInterval(d time.Duration) Option {
+	return func(p *ioProgress) {
+		if d >= 0 {
+			p.interval = d
+		}
+	}
+}
+
+// WithSpeedWindow sets the period over which the current speed is calculated,
+// DefaultSpeedWindow by default. A short window makes the speed react fast to
+// changes, a long one makes it more stable.
+func WithSpeedWindow(d time.Duration) Option {
+	return func(p *ioProgress) {
+		if d > 0 {
+			p.window = d
+		}
+	}
+}
+
+// WithMinBytesDelta sets the minimum amount of bytes that have to be
+// transferred since the last update to send a new one. The final update is
+// always sent.
+func WithMinBytesDelta(n int64) Option {
+	return func(p *ioProgress) {
+		if n >= 0 {
+			p.minDelta = n
+		}
+	}
+}
+
+// WithClock sets the Clock used to calculate the progress, the system time by
+// default. The rate limiting also waits using the Clock if it is a TimerClock.
+func WithClock(c Clock) Option {
+	return func(p *ioProgress) {
+		if c != nil {
+			p.clock = c
+		}
+	}
+}
+
+// WithEstimator sets the Estimator used to estimate the time remaining, an
+// AverageEstimator by default. Every wrapper needs its own Estimator instance.
+func WithEstimator(e Estimator) Option {
+	return func(p *ioProgress) {
+		if e != nil {
+			p.estimator = e
+		}
+	}
+}
+
+// WithRateLimit limits the throughput to bytesPerSec bytes per second, with
+// bursts of up to burst bytes, see NewRateLimiter.
+func WithRateLimit(bytesPerSec, burst int64) Option {
+	return func(p *ioProgress) {
+		p.limiter = NewRateLimiter(bytesPerSec, burst)
+	}
+}
+
+// WithRateLimiter limits the throughput using the RateLimiter, which can be
+// shared between several wrappers to keep them under one global limit.
+func WithRateLimiter(l *RateLimiter) Option {
+	return func(p *ioProgress) {
+		if l != nil {
+			p.limiter = l
+		}
+	}
+}
+
+// WithContext binds the transfer to the context: once it is cancelled, Read and
+// Write return the context's error, and the final Progress carrying that error
+// is sent.
+func WithContext(ctx context.Context) Option {
+	return func(p *ioProgress) {
+		if ctx != nil {
+			p.ctx = ctx
+		}
+	}
+}
+
+// WithInitialOffset starts the transfer at n bytes, which were already
+// transferred before, e.g. when resuming an interrupted download. They count
+// toward Transferred and Percent, but not toward the Session, the speed and the
+// time remaining. Seeking moves the progress like the offset: seeking to 0 and
+// transferring 10 bytes gives a Transferred and Session of 10.
+func WithInitialOffset(n int64) Option {
+	return func(p *ioProgress) {
+		if n > 0 {
+			p.progress = n
+			p.offset = n
+			p.pos = n
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package progressio
+
+import (
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bartmeuris/progressio/progressiotest"
+)
+
+func TestOptionsDelivery(t *testing.T) {
+	var calls []Progress
+	buffered := make(chan Progress, 100)
+	unread := make(chan Progress)
+	guaranteed := make(chan Progress)
+	final := make(chan Progress)
+	go func() {
+		last := Progress{}
+		for p := range guaranteed {
+			last = p
+		}
+		final <- last
+	}()
+
+	r := NewProgressReaderWithOptions(strings.NewReader(strings.Repeat("x", 1000)), 1000,
+		WithCallback(func(p Progress) { calls = append(calls, p) }),
+		WithChannel(buffered),
+		WithChannel(unread),
+		WithChannel(guaranteed),
+		WithGuaranteedFinal(),
+	)
+	go func() {
+		// Intermediate updates are dropped while nobody is receiving, but the
+		// guaranteed final update waits for this
+		for range unread {
+		}
+	}()
+	io.Copy(ioutil.Discard, r)
+	r.Close()
+
+	if len(calls) == 0 || calls[len(calls)-1].Transferred != 1000 || calls[len(calls)-1].StopTime.IsZero() {
+		t.Errorf("Callback: did not receive the final update")
+	}
+	var last Progress
+	for p := range buffered {
+		last = p
+	}
+	if last.Transferred != 1000 || last.StopTime.IsZero() {
+		t.Errorf("Buffered channel: did not receive the final update")
+	}
+	if p := <-final; p.Transferred != 1000 || p.StopTime.IsZero() {
+		t.Errorf("Guaranteed channel: did not receive the final update")
+	}
+}
+
+func TestOptionsBestEffortFinal(t *testing.T) {
+	ch := make(chan Progress)
+	w := NewProgressWriterWithOptions(ioutil.Discard, 10, WithChannel(ch))
+	// Nobody is receiving: this must not block
+	w.Write(make([]byte, 10))
+	w.Close()
+	if _, ok := <-ch; ok {
+		t.Errorf("Channel: expected it to be closed without updates")
+	}
+}
+
+func TestOptionsTuning(t *testing.T) {
+	clock := progressiotest.NewClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	var calls []Progress
+	w := NewProgressWriterWithOptions(ioutil.Discard, -1,
+		WithCallback(func(p Progress) { calls = append(calls, p) }),
+		WithClock(clock),
+		WithUpdateInterval(time.Second),
+		WithSpeedWindow(2*time.Second),
+		WithMinBytesDelta(100),
+	)
+	step := func(d time.Duration, n int) {
+		clock.Advance(d)
+		w.Write(make([]byte, n))
+	}
+
+	step(0, 100) // first update is always sent
+	step(0, 100) // throttled: interval
+	step(time.Second, 50)
+	step(time.Second, 50) // throttled: min bytes delta
+	step(time.Second, 100)
+	step(time.Second, 200)
+	w.Close()
+
+	expect := []struct {
+		transferred int64
+		speed       int64
+		speedAvg    int64
+	}{
+		{100, -1, -1},
+		{250, 150, 250},
+		{400, 75, 133},
+		{600, 116, 150},
+		{600, 116, 150},
+	}
+	if len(calls) != len(expect) {
+		t.Fatalf("Got %d updates, expected %d", len(calls), len(expect))
+	}
+	for i, e := range expect {
+		p := calls[i]
+		if p.Transferred != e.transferred || p.Speed != e.speed || p.SpeedAvg != e.speedAvg {
+			t.Errorf("Update %d: got transferred %d, speed %d, avg %d, expected %d, %d, %d",
+				i, p.Transferred, p.Speed, p.SpeedAvg, e.transferred, e.speed, e.speedAvg)
+		}
+	}
+}
+
+func TestInitialOffset(t *testing.T) {
+	clock := progressiotest.NewClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	var last Progress
+	w := NewProgressWriterWithOptions(ioutil.Discard, 1000,
+		WithCallback(func(p Progress) { last = p }),
+		WithClock(clock),
+		WithUpdateInterval(0),
+		WithInitialOffset(400),
+	)
+	defer w.Close()
+	w.Write(make([]byte, 100))
+	clock.Advance(time.Second)
+	w.Write(make([]byte, 100))
+
+	if last.Transferred != 600 || last.Session != 200 || last.Percent != 60 {
+		t.Errorf("Got transferred %d, session %d, %.2f%%, expected 600, 200, 60%%",
+			last.Transferred, last.Session, last.Percent)
+	}
+	// The offset is not part of the speed and the time remaining
+	if last.SpeedAvg != 200 || last.Remaining != 2*time.Second {
+		t.Errorf("Got avg %d, remaining %v, expected 200, 2s", last.SpeedAvg, last.Remaining)
+	}
+}
+
+func TestInitialOffsetSeek(t *testing.T) {
+	clock := progressiotest.NewClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	var last Progress
+	r := NewProgressReaderWithOptions(strings.NewReader(strings.Repeat("x", 1000)), 1000,
+		WithCallback(func(p Progress) { last = p }),
+		WithClock(clock),
+		WithUpdateInterval(0),
+		WithInitialOffset(100),
+	)
+	defer r.Close()
+	b := make([]byte, 10)
+	// Starting over from the start: nothing is transferred yet
+	r.Seek(0, io.SeekStart)
+	r.Read(b)
+	clock.Advance(time.Second)
+	r.Read(b)
+	if last.Transferred != 20 || last.Session != 20 || last.SpeedAvg != 20 {
+		t.Errorf("After seeking to 0: got transferred %d, session %d, avg %d, expected 20, 20, 20",
+			last.Transferred, last.Session, last.SpeedAvg)
+	}
+	// Skipping ahead is no transfer either
+	r.Seek(500, io.SeekStart)
+	r.Read(b)
+	if last.Transferred != 510 || last.Session != 30 {
+		t.Errorf("After seeking to 500: got transferred %d, session %d, expected 510, 30", last.Transferred, last.Session)
+	}
+}
+
+func TestKeepOpen(t *testing.T) {
+	var last Progress
+	r := NewProgressReaderWithOptions(strings.NewReader("some data"), 9,
+		WithCallback(func(p Progress) { last = p }),
+		WithKeepOpen(),
+	)
+	io.Copy(ioutil.Discard, r)
+	if !last.StopTime.IsZero() {
+		t.Errorf("Reaching EOF finished the transfer: %+v", last)
+	}
+	// The data can be read again
+	r.Seek(0, io.SeekStart)
+	io.Copy(ioutil.Discard, r)
+	r.Close()
+	if last.StopTime.IsZero() || last.State != StateDone || last.Transferred != 9 {
+		t.Errorf("Got final progress %+v, expected done at 9 bytes", last)
+	}
+}
@@ -0,0 +1,84 @@
+package progressio
+
+// Pause pauses the transfer: Read and Write block until Resume is called, the
+// transfer is closed, or the context it is bound to is done. The time the
+// transfer is paused is left out of the average speed and the time remaining.
+// An update with the StatePaused state is sent right away.
+func (p *ioProgress) Pause() {
+	p.mu.Lock()
+	if p.paused || p.finished {
+		p.mu.Unlock()
+		return
+	}
+	p.paused = true
+	p.pausedAt = p.clock.Now()
+	p.resume = make(chan struct{})
+	p.release(p.update(0, true))
+}
+
+// Resume resumes a paused transfer, unblocking Read and Write. An update with
+// the StateRunning state is sent right away.
+func (p *ioProgress) Resume() {
+	p.mu.Lock()
+	if !p.paused {
+		p.mu.Unlock()
+		return
+	}
+	p.unpause()
+	p.release(p.update(0, true))
+}
+
+// Paused returns if the transfer is paused.
+func (p *ioProgress) Paused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.paused
+}
+
+// unpause ends the pause, p.mu must be held
+func (p *ioProgress) unpause() {
+	if !p.paused {
+		return
+	}
+	p.pausedFor += p.clock.Now().Sub(p.pausedAt)
+	p.paused = false
+	close(p.resume)
+}
+
+// waitResume blocks while the transfer is paused. If the context the transfer
+// is bound to is done while waiting, the transfer is stopped and the context's
+// error is returned.
+func (p *ioProgress) waitResume() error {
+	p.mu.Lock()
+	paused, resume := p.paused, p.resume
+	p.mu.Unlock()
+	if !paused {
+		return nil
+	}
+	var done <-chan struct{}
+	if p.ctx != nil {
+		done = p.ctx.Done()
+	}
+	select {
+	case <-resume:
+		return nil
+	case <-done:
+		return p.ctxErr()
+	}
+}
+
+// Pause pauses all the members of the group, see ProgressReader.Pause.
+func (g *ProgressGroup) Pause() {
+	for _, m := range g.memberList() {
+		m.Pause()
+	}
+	g.ioProgress.Pause()
+}
+
+// Resume resumes all the members of the group.
+func (g *ProgressGroup) Resume() {
+	for _, m := range g.memberList() {
+		m.Resume()
+	}
+	g.ioProgress.Resume()
+}
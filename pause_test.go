@@ -0,0 +1,133 @@
+package progressio
+
+import (
+	"context"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bartmeuris/progressio/progressiotest"
+)
+
+func TestPauseResume(t *testing.T) {
+	clock := progressiotest.NewClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	var mu sync.Mutex
+	var last Progress
+	w := NewProgressWriterWithOptions(ioutil.Discard, 1000,
+		WithClock(clock),
+		WithUpdateInterval(0),
+		WithCallback(func(p Progress) {
+			mu.Lock()
+			last = p
+			mu.Unlock()
+		}),
+	)
+	get := func() Progress {
+		mu.Lock()
+		defer mu.Unlock()
+		return last
+	}
+
+	w.Write(make([]byte, 100))
+	clock.Advance(time.Second)
+	w.Write(make([]byte, 100))
+	if p := get(); p.State != StateRunning || p.SpeedAvg != 200 {
+		t.Errorf("Before pause: got state %v, avg %d, expected running, 200", p.State, p.SpeedAvg)
+	}
+
+	w.Pause()
+	if p := get(); p.State != StatePaused || p.Speed != 0 || !w.Paused() {
+		t.Errorf("Paused: got state %v, speed %d, expected paused, 0", p.State, p.Speed)
+	}
+	written := make(chan struct{})
+	go func() {
+		w.Write(make([]byte, 100))
+		close(written)
+	}()
+	select {
+	case <-written:
+		t.Fatalf("Write did not block while paused")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	clock.Advance(10 * time.Second)
+	w.Resume()
+	<-written
+	if p := get(); p.State != StateRunning || p.SpeedAvg != 300 {
+		t.Errorf("After resume: got state %v, avg %d, expected running, 300", p.State, p.SpeedAvg)
+	}
+	clock.Advance(time.Second)
+	w.Write(make([]byte, 700))
+	p := get()
+	if p.State != StateDone || p.SpeedAvg != 500 {
+		t.Errorf("Done: got state %v, avg %d, expected done, 500", p.State, p.SpeedAvg)
+	}
+	if p.Elapsed() != 12*time.Second {
+		t.Errorf("Done: got elapsed %v, expected 12s", p.Elapsed())
+	}
+}
+
+func TestPauseUnblock(t *testing.T) {
+	// Closing unblocks a paused transfer
+	r, ch := NewProgressReader(zeroReader{}, -1)
+	go func() {
+		for range ch {
+		}
+	}()
+	r.Pause()
+	done := make(chan struct{})
+	go func() {
+		r.Read(make([]byte, 10))
+		close(done)
+	}()
+	r.Close()
+	<-done
+
+	// And so does cancelling the context
+	ctx, cancel := context.WithCancel(context.Background())
+	w, wch := NewProgressWriterContext(ctx, ioutil.Discard, -1)
+	final := make(chan Progress)
+	go func() {
+		var last Progress
+		for p := range wch {
+			last = p
+		}
+		final <- last
+	}()
+	w.Pause()
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if _, err := w.Write(make([]byte, 10)); err != context.Canceled {
+		t.Errorf("Write() error = %v, expected %v", err, context.Canceled)
+	}
+	w.Close()
+	if p := <-final; p.State != StateFailed {
+		t.Errorf("Final state %v, expected failed", p.State)
+	}
+}
+
+func TestStateString(t *testing.T) {
+	for s, want := range map[State]string{StateRunning: "running", StatePaused: "paused", StateDone: "done", StateFailed: "failed", State(9): "State(9)"} {
+		if got := s.String(); got != want {
+			t.Errorf("State(%d).String() = %q, want %q", int(s), got, want)
+		}
+	}
+}
+
+func TestGroupPause(t *testing.T) {
+	g, ch := NewProgressGroup()
+	go func() {
+		for range ch {
+		}
+	}()
+	w := g.NewWriter(ioutil.Discard, 100)
+	g.Pause()
+	if !w.Paused() || !g.Paused() {
+		t.Errorf("Pausing the group did not pause its members")
+	}
+	g.Resume()
+	if w.Paused() || g.Paused() {
+		t.Errorf("Resuming the group did not resume its members")
+	}
+	g.Close()
+}
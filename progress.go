@@ -9,7 +9,6 @@ progressio.ProgressWriter objects in order to clean everything up.
 
 Usage is pretty simple:
 
-
 	preader, pchan := progressio.NewProgressReader(myreader, -1)
 	defer preader.Close()
 	go func() {
@@ -20,11 +19,9 @@ Usage is pretty simple:
 	// read from your new reader object
 	io.Copy(mywriter, preader)
 
-
 A helper function is available that opens a file, determines it's size, and
 wraps it's os.File io.Reader object:
 
-
 	if pr, pc, err := progressio.NewProgressFileReader(myfile); err != nil {
 		return err
 	} else {
@@ -38,14 +35,12 @@ wraps it's os.File io.Reader object:
 		io.Copy(mywriter, pr)
 	}
 
-
 A wrapper for an io.WriterCloser is available too, but no helper function
 is available to write to an os.File since the target size is not known.
 Usually, wrapping the io.Writer is more accurate, since writing potentially
 takes up more time and happens last. Useage is similar to wrapping the
 io.Reader:
 
-
 	pwriter, pchan := progressio.NewProgressWriter(mywriter, -1)
 	defer pwriter.Close()
 	go func() {
@@ -56,50 +51,158 @@ io.Reader:
 	// write to your new writer object
 	io.Copy(pwriter, myreader)
 
+If the transfer has to be abortable, use the Context variants of the
+constructors. Once the context is cancelled, Read and Write return the
+context's error, and a final Progress with the StopTime and the reason in Err
+is sent before the channel is closed:
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	preader, pchan := progressio.NewProgressReaderContext(ctx, myreader, -1)
+	defer preader.Close()
 
 Note that you can also implement your own formatting. See the String() function
 implementation or consult the Progress struct layout and documentation
-
 */
 package progressio
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"sync"
 	"time"
 )
 
 // Frequency of the updates over the channels
 const UpdateFreq = 100 * time.Millisecond
-const timeSlots = 5
 
-// Progress is the object sent back over the progress channel.
+// DefaultSpeedWindow is the period over which the current speed is calculated
+const DefaultSpeedWindow = 2 * time.Second
+
+// The amount of samples kept to calculate the current speed over the speed window
+const speedSamples = 10
+
+// ErrIncomplete is the reason a transfer of a known size failed when it was
+// stopped, by closing it or by reaching EOF, before the whole size was
+// transferred. It wraps io.ErrUnexpectedEOF.
+var ErrIncomplete = fmt.Errorf("progressio: stopped before the whole size was transferred: %w", io.ErrUnexpectedEOF)
+
+// State is the state of a transfer
+type State int
+
+// The states a transfer can be in
+const (
+	StateRunning State = iota // The transfer is running
+	StatePaused               // The transfer is paused
+	StateDone                 // The transfer completed successfully
+	StateFailed               // The transfer was aborted
+)
+
+var stateNames = []string{"running", "paused", "done", "failed"}
+
+// String returns the name of the state
+func (s State) String() string {
+	if s < 0 || int(s) >= len(stateNames) {
+		return fmt.Sprintf("State(%d)", int(s))
+	}
+	return stateNames[s]
+}
+
+// MarshalText encodes the state as its name.
+func (s State) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes the name of a state.
+func (s *State) UnmarshalText(text []byte) error {
+	for i, name := range stateNames {
+		if name == string(text) {
+			*s = State(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("progressio: unknown state %q", text)
+}
+
+// Progress is the object sent back over the progress channel. See MarshalJSON for
+// its JSON representation.
 type Progress struct {
-	Transferred int64         // Transferred data in bytes
+	Transferred int64         // Transferred data in bytes, including the initial offset
+	Session     int64         // Data transferred by this wrapper in bytes, excluding the initial offset and seeks
 	TotalSize   int64         // Total size of the transfer in bytes. <= 0 if size is unknown.
 	Percent     float64       // If the size is known, the progress of the transfer in %
 	SpeedAvg    int64         // Bytes/sec average over the entire transfer
 	Speed       int64         // Bytes/sec of the last few reads/writes
 	Remaining   time.Duration // Estimated time remaining, only available if the size is known.
+	Estimator   string        // Name of the Estimator which estimated the time remaining
+	Confidence  float64       // Confidence in the estimated time remaining, between 0 and 1
 	StartTime   time.Time     // When the transfer was started
 	StopTime    time.Time     // only specified when the transfer is completed: when the transfer was stopped
+	Err         error         // only specified when the transfer failed or was aborted: the reason why it was stopped
+	Throttled   bool          // If the transfer is currently being slowed down by a rate limit
+	State       State         // The state of the transfer
+
+	clock Clock // the clock of the wrapper which sent the progress, used to format it
 }
 
+// ioProgress holds the state shared by the wrappers. All fields below mu are
+// protected by it, so a single wrapper can be used from several goroutines.
+// The updates are delivered without holding mu, see release.
 type ioProgress struct {
+	sendMu    sync.Mutex // serializes the delivery of the updates
+	mu        sync.Mutex
+	ctx       context.Context
+	err       error
 	size      int64
 	progress  int64
-	ch        chan Progress
+	offset    int64             // the progress not transferred by this wrapper: the initial offset, moved by seeks
+	pos       int64             // the position of the sequential reads and writes
+	subs      []chan<- Progress // subscriber channels, closed when done, protected by sendMu
+	callbacks []func(Progress)  // callbacks called with every update
+	guarantee bool              // block until the final update is delivered
 	closed    bool
+	finished  bool           // the final update was prepared, nothing more to do
+	done      chan struct{}  // closed once the final update is delivered
+	keepOpen  bool           // only stop on close, not on EOF or when progress reaches size
+	group     *ProgressGroup // the group this progress is a member of, if any
+	clock     Clock
+	interval  time.Duration // minimum time between updates
+	minDelta  int64         // minimum amount of bytes between updates
+	window    time.Duration // period over which the current speed is calculated
+	estimator Estimator     // estimates the time remaining
+	limiter   *RateLimiter  // limits the throughput, if set
+	throttled bool          // the last read/write had to wait for the limiter
+	paused    bool          // Read/Write block until resumed
+	pausedAt  time.Time     // when the transfer was paused
+	pausedFor time.Duration // the total time the transfer was paused, excluding the current pause
+	resume    chan struct{} // closed when the transfer is resumed
 	startTime time.Time
-	lastSent  time.Time
-	updatesW  []int64
-	updatesT  []time.Time
-	ts        int
+	lastSent  time.Time // when the last update was sent, delivered or not
+	lastBytes int64     // progress at the time of the last update sent
+	samples   []sample  // progress samples to calculate the current speed with
+	ranges    *rangeSet // the ranges transferred at random offsets, if any
+}
+
+// delivery is an update prepared by update while holding p.mu, which is
+// delivered by release once p.mu is released.
+type delivery struct {
+	prog  Progress
+	group *ProgressGroup
+	final bool
+}
+
+// sample is the progress at a certain point in time
+type sample struct {
+	t time.Time
+	n int64
 }
 
 // String returns a string representation of the progress. It takes into account
 // if the size was known, and only tries to display relevant data.
 func (p *Progress) String() string {
-	timeS := fmt.Sprintf(" (Time: %s", FormatDuration(time.Since(p.StartTime)))
+	timeS := fmt.Sprintf(" (Time: %s", FormatDuration(p.Elapsed()))
 	// Build the Speed string
 	speedS := ""
 	if p.Speed > 0 {
@@ -147,67 +250,123 @@ func (p *Progress) String() string {
 	)
 }
 
-func mkIoProgress(size int64) *ioProgress {
-	return &ioProgress{
+// Elapsed returns the duration of the transfer: up to the StopTime if the transfer
+// is completed, up to now otherwise. The current time is determined by the Clock of
+// the wrapper which sent the progress. It is 0 if the StartTime is not set.
+func (p *Progress) Elapsed() time.Duration {
+	if p.StartTime.IsZero() {
+		return 0
+	}
+	if !p.StopTime.IsZero() {
+		return p.StopTime.Sub(p.StartTime)
+	}
+	if p.clock == nil {
+		return time.Since(p.StartTime)
+	}
+	return p.clock.Now().Sub(p.StartTime)
+}
+
+func mkIoProgress(size int64, opts ...Option) *ioProgress {
+	ret := &ioProgress{
 		size:      size,
 		progress:  0,
-		ch:        make(chan Progress),
 		closed:    false,
+		done:      make(chan struct{}),
+		clock:     systemClock{},
+		interval:  UpdateFreq,
+		window:    DefaultSpeedWindow,
 		startTime: time.Time{},
 		lastSent:  time.Time{},
-		updatesW:  make([]int64, timeSlots),
-		updatesT:  make([]time.Time, timeSlots),
-		ts:        0,
 	}
+	for _, opt := range opts {
+		opt(ret)
+	}
+	if ret.estimator == nil {
+		ret.estimator = NewAverageEstimator()
+	}
+	return ret
 }
 
 func (p *ioProgress) updateProgress(written int64) {
-	if p.closed && p.ch == nil {
+	p.mu.Lock()
+	start := p.pos
+	p.pos += written
+	if p.ranges != nil {
+		// Once ranges are used, the sequential transfer only counts the bytes
+		// which were not transferred before either
+		written = p.ranges.add(start, p.pos)
+	}
+	p.release(p.update(written, false))
+}
+
+// update does the actual work of updateProgress, p.mu must be held. It returns
+// the update to deliver using release, nil if there is none. Once the final
+// update is prepared, the progress is finished, so concurrent callers find it
+// closed. If force is set, the update is not throttled.
+func (p *ioProgress) update(written int64, force bool) *delivery {
+	if p.finished {
 		// Nothing to do
-		return
+		return nil
 	}
 	if written > 0 {
 		p.progress += written
+		if p.group != nil {
+			p.group.memberWritten(written)
+		}
 	}
-	// Throttle sending updated, limit to UpdateFreq - which should be 100ms
+	// Throttle sending updates, limit to one per interval - which is UpdateFreq by
+	// default - and to updates of at least minDelta bytes. This also limits the
+	// speed and estimator samples, whether or not the update is delivered.
 	// Always send when finished
-	if (time.Since(p.lastSent) < UpdateFreq) && ((p.size > 0) && (p.progress != p.size)) {
-		return
+	now := p.clock.Now()
+	final := p.closed || (p.progress == p.size && !p.keepOpen)
+	if !final && !force && (now.Sub(p.lastSent) < p.interval || p.progress-p.lastBytes < p.minDelta) {
+		return nil
 	}
 	if p.startTime.IsZero() {
-		p.startTime = time.Now()
+		p.startTime = now
+	}
+	// The speed and time remaining are calculated using the time the transfer
+	// was active: the time it was paused is left out.
+	active := now.Add(-p.pausedFor)
+	if p.paused {
+		active = p.pausedAt.Add(-p.pausedFor)
 	}
 
 	prog := Progress{
 		StartTime:   p.startTime,
 		Transferred: p.progress,
+		Session:     p.progress - p.offset,
 		TotalSize:   p.size,
+		Speed:       -1,
+		SpeedAvg:    -1,
+		Remaining:   -1,
+		Throttled:   p.throttled,
+		State:       StateRunning,
+		clock:       p.clock,
 	}
 
-	// Calculate current speed based on the last `timeSlots` updates sent
-	p.updatesW[p.ts%timeSlots] = p.progress
-	p.updatesT[p.ts%timeSlots] = time.Now()
-	p.ts++
-	if !p.updatesT[p.ts%timeSlots].IsZero() {
-		// Calculate the average speed of the last ~2 seconds
-		prog.Speed = int64((float64(p.progress-p.updatesW[p.ts%timeSlots]) / float64(time.Since(p.updatesT[p.ts%timeSlots]))) * float64(time.Second))
+	// Calculate the current speed over the speed window, nothing is transferred
+	// while paused
+	p.addSample(active)
+	if p.paused {
+		prog.State = StatePaused
+		prog.Speed = 0
+	} else if base := p.samples[0]; active.After(base.t) {
+		prog.Speed = int64((float64(p.progress-base.n) / float64(active.Sub(base.t))) * float64(time.Second))
+	}
 
-		// Calculate the average speed since starting the transfer
-		tp := time.Since(p.startTime)
-		if tp > 0 {
-			prog.SpeedAvg = int64((float64(p.progress) / float64(tp)) * float64(time.Second))
-		} else {
-			prog.SpeedAvg = -1
-		}
-		if p.size > 0 && prog.SpeedAvg > 0 {
-			prog.Remaining = time.Duration((float64(p.size-p.progress) / float64(prog.SpeedAvg)) * float64(time.Second))
-		} else {
-			prog.Remaining = -1
-		}
-	} else {
-		prog.Speed = -1
-		prog.SpeedAvg = -1
-		prog.Remaining = -1
+	// Calculate the average speed since starting the transfer, the initial
+	// offset was not transferred in that time
+	if tp := active.Sub(p.startTime); tp > 0 {
+		prog.SpeedAvg = int64((float64(prog.Session) / float64(tp)) * float64(time.Second))
+	}
+
+	// Estimate the time remaining only if we have a size
+	p.estimator.Sample(active, prog.Session)
+	if p.size > 0 {
+		prog.Estimator = p.estimator.Name()
+		prog.Remaining, prog.Confidence = p.estimator.Estimate(p.size - p.progress)
 	}
 
 	// Calculate the percentage only if we have a size
@@ -215,34 +374,377 @@ func (p *ioProgress) updateProgress(written int64) {
 		prog.Percent = float64(int64((float64(p.progress)/float64(p.size))*10000.0)) / 100.0
 	}
 
-	if p.closed || p.progress == p.size {
+	if final {
 		// EOF or closed, we have to send this last message, and then close the chan
 		// Prevent sending the last message multiple times
-		if p.ch != nil {
-			prog.StopTime = time.Now()
-			p.ch <- prog
-			p.cleanup()
+		prog.StopTime = now
+		prog.Err = p.err
+		prog.State = StateDone
+		if p.err != nil {
+			prog.State = StateFailed
+		}
+		p.closed = true
+		p.finished = true
+	}
+	p.lastSent = now
+	p.lastBytes = p.progress
+	return &delivery{prog: prog, group: p.group, final: final}
+}
+
+// release unlocks p.mu, which must be held, and delivers the update prepared by
+// update, if any. The receivers are free to call the methods of the wrapper,
+// as p.mu is not held while delivering. sendMu is taken before unlocking p.mu,
+// so the updates are delivered in the order they were prepared. Only the final
+// update can block, after which no more updates are prepared.
+func (p *ioProgress) release(d *delivery) {
+	if d == nil {
+		p.mu.Unlock()
+		return
+	}
+	p.sendMu.Lock()
+	defer p.sendMu.Unlock()
+	p.mu.Unlock()
+	p.send(d, d.final && p.guarantee)
+	if d.final {
+		p.cleanup()
+	}
+}
+
+// addSample records the current progress to calculate the current speed with.
+// Samples are spaced evenly over the speed window, and samples which are no
+// longer needed are dropped: the oldest sample kept is the newest one at or
+// before the start of the window.
+func (p *ioProgress) addSample(now time.Time) {
+	spacing := p.window / speedSamples
+	if n := len(p.samples); n == 0 || now.Sub(p.samples[n-1].t) >= spacing {
+		p.samples = append(p.samples, sample{now, p.progress})
+	}
+	start := now.Add(-p.window)
+	i := 0
+	for i < len(p.samples)-1 && !p.samples[i+1].t.After(start) {
+		i++
+	}
+	p.samples = p.samples[i:]
+}
+
+// send delivers the progress to the group, the callbacks and the subscribers.
+// Unless block is set, it is only sent to the subscribers that are ready to
+// receive it.
+func (p *ioProgress) send(d *delivery, block bool) {
+	if d.group != nil {
+		d.group.memberProgress(p, d.prog)
+	}
+	for _, fn := range p.callbacks {
+		fn(d.prog)
+	}
+	for _, ch := range p.subs {
+		if block {
+			ch <- d.prog
+			continue
 		}
-	} else {
-		// Don't force send, only send when it would not block, the chan is non-buffered
 		select {
-		case p.ch <- prog:
-			// update last sent values
-			p.lastSent = time.Now()
+		case ch <- d.prog:
 		default:
 		}
 	}
 }
 
+// cleanup closes the channels after the final update was delivered, p.sendMu
+// must be held.
 func (p *ioProgress) cleanup() {
-	p.closed = true
-	if p.ch != nil {
-		close(p.ch)
-		p.ch = nil
+	for _, ch := range p.subs {
+		close(ch)
 	}
+	p.subs = nil
+	close(p.done)
 }
 
-func (p *ioProgress) stopProgress() {
+// stopProgress marks the transfer as stopped and sends the final update. The
+// err parameter is the reason the transfer failed or was aborted, nil if it
+// completed. A transfer of a known size which is stopped before the whole size
+// was transferred fails with ErrIncomplete. Only the first reason is retained.
+func (p *ioProgress) stopProgress(err error) {
+	p.mu.Lock()
+	if p.finished {
+		p.mu.Unlock()
+		return
+	}
+	if err == nil && p.size > 0 && p.progress < p.size {
+		err = ErrIncomplete
+	}
+	if p.err == nil {
+		p.err = err
+	}
 	p.closed = true
-	p.updateProgress(-1)
+	p.unpause()
+	p.release(p.update(-1, false))
+}
+
+// finish stops the transfer after reading ended with err: nil or io.EOF means
+// the transfer completed, unless it is kept open, any other error that it
+// failed.
+func (p *ioProgress) finish(err error) {
+	if err == nil || err == io.EOF {
+		p.mu.Lock()
+		keepOpen := p.keepOpen
+		p.mu.Unlock()
+		if keepOpen {
+			return
+		}
+		err = nil
+	}
+	p.stopProgress(err)
+}
+
+// Wait blocks until the transfer is finished: when it completed, failed, or was
+// closed. It returns nil if the transfer completed successfully, and the error
+// why it failed otherwise, ErrIncomplete if it stopped before the whole size
+// was transferred.
+func (p *ioProgress) Wait() error {
+	<-p.done
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// seekProgress moves the progress to pos after a seek: seeking back means the
+// data is transferred again. No update is sent, as seeking is no transfer (e.g.
+// seeking to the end to find the size), the next read or write sends it. The
+// offset moves along, so the session only counts the bytes transferred. Once
+// ranges are used, only the position moves: the ranges count every byte once.
+func (p *ioProgress) seekProgress(pos int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.finished {
+		return
+	}
+	p.pos = pos
+	if p.ranges != nil {
+		return
+	}
+	delta := pos - p.progress
+	p.progress = pos
+	p.offset += delta
+	if p.lastBytes > pos {
+		p.lastBytes = pos
+	}
+	// Restart calculating the current speed from the new position
+	p.samples = nil
+	if p.group != nil && delta != 0 {
+		p.group.memberMoved(delta)
+	}
+}
+
+// rangeProgress updates the progress after n bytes were transferred at offset
+// off, counting only the bytes which were not transferred before.
+func (p *ioProgress) rangeProgress(off, n int64) {
+	p.mu.Lock()
+	if p.ranges == nil {
+		p.ranges = p.newRangeSet()
+	}
+	p.release(p.update(p.ranges.add(off, off+n), false))
+}
+
+// newRangeSet creates the set of ranges transferred, containing the bytes
+// counted so far: the initial offset, followed by the sequential transfer.
+func (p *ioProgress) newRangeSet() *rangeSet {
+	s := &rangeSet{}
+	s.add(0, p.progress)
+	return s
+}
+
+// readAt reads from ra at offset off, counting the bytes which were not read
+// before. Reading past the end is no reason to stop the transfer.
+func (p *ioProgress) readAt(ra io.ReaderAt, b []byte, off int64) (n int, err error) {
+	if err = p.ctxErr(); err != nil {
+		return 0, err
+	}
+	if err = p.waitResume(); err != nil {
+		return 0, err
+	}
+	n, err = ra.ReadAt(b, off)
+	wait := p.reserve(int64(n))
+	p.rangeProgress(off, int64(n))
+	if terr := p.sleep(wait); terr != nil && err == nil {
+		err = terr
+	}
+	if err != nil && err != io.EOF {
+		p.stopProgress(err)
+	}
+	return
+}
+
+// writeAt writes to wa at offset off, counting the bytes which were not
+// written before.
+func (p *ioProgress) writeAt(wa io.WriterAt, b []byte, off int64) (n int, err error) {
+	if err = p.ctxErr(); err != nil {
+		return 0, err
+	}
+	if err = p.waitResume(); err != nil {
+		return 0, err
+	}
+	if err = p.throttle(int64(len(b))); err != nil {
+		return 0, err
+	}
+	n, err = wa.WriteAt(b, off)
+	p.rangeProgress(off, int64(n))
+	if err != nil {
+		p.stopProgress(err)
+	}
+	return
+}
+
+// coverage returns the ranges transferred at random offsets, and the holes
+// in between them up to the size of the transfer.
+func (p *ioProgress) coverage() (covered, holes []Range) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.ranges == nil {
+		p.ranges = p.newRangeSet()
+	}
+	return p.ranges.list(), p.ranges.holes(p.size)
+}
+
+// The maximum amount of bytes copied at once by copyChunks without rate limit
+const copyChunkSize = 256 * 1024
+
+// copyChunks copies from r to rf until EOF or an error, in chunks so the
+// progress is updated while the fast paths of rf, like sendfile or splice, are
+// kept: they also recognize r wrapped in an io.LimitedReader. If r is an
+// io.LimitedReader itself, it is unwrapped, so stacked wrappers keep them too.
+func (p *ioProgress) copyChunks(rf io.ReaderFrom, r io.Reader) (n int64, err error) {
+	lr, limited := r.(*io.LimitedReader)
+	for {
+		if err = p.ctxErr(); err != nil {
+			return
+		}
+		if err = p.waitResume(); err != nil {
+			return
+		}
+		chunk := int64(p.chunkSize())
+		if chunk <= 0 {
+			chunk = copyChunkSize
+		}
+		src := r
+		if limited {
+			if lr.N <= 0 {
+				return
+			}
+			if lr.N < chunk {
+				chunk = lr.N
+			}
+			src = lr.R
+		}
+		var m int64
+		m, err = rf.ReadFrom(&io.LimitedReader{R: src, N: chunk})
+		n += m
+		if limited {
+			lr.N -= m
+		}
+		wait := p.reserve(m)
+		p.updateProgress(m)
+		if terr := p.sleep(wait); terr != nil && err == nil {
+			err = terr
+		}
+		// Less than a chunk means r reached EOF
+		if err != nil || m < chunk {
+			return
+		}
+	}
+}
+
+// unsupported returns the error of a method the wrapped object v does not
+// support, as it does not implement the interface named iface.
+func unsupported(v interface{}, iface string) error {
+	return fmt.Errorf("progressio: %T does not implement %s: %w", v, iface, errors.ErrUnsupported)
+}
+
+// SetRateLimit limits the throughput to bytesPerSec bytes per second, with bursts
+// of up to burst bytes, see NewRateLimiter. A bytesPerSec <= 0 removes the limit.
+// If a RateLimiter was specified with WithRateLimiter, its limits are changed, so
+// this affects all the wrappers sharing it.
+func (p *ioProgress) SetRateLimit(bytesPerSec, burst int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.limiter == nil {
+		p.limiter = NewRateLimiter(bytesPerSec, burst)
+	} else {
+		p.limiter.SetRate(bytesPerSec, burst)
+	}
+}
+
+// chunkSize returns the maximum amount of bytes to transfer at once, 0 if there
+// is no maximum.
+func (p *ioProgress) chunkSize() int {
+	p.mu.Lock()
+	l := p.limiter
+	p.mu.Unlock()
+	if l == nil {
+		return 0
+	}
+	return l.chunkSize()
+}
+
+// throttle waits until the rate limiter allows n more bytes to be transferred.
+// If the context the transfer is bound to is done while waiting, the transfer
+// is stopped and the context's error is returned.
+func (p *ioProgress) throttle(n int64) error {
+	return p.sleep(p.reserve(n))
+}
+
+// reserve takes n bytes from the rate limiter, and returns how long to wait
+// before transferring more. The next update reports if the transfer is
+// throttled, so after reading, reserve has to be called before updating.
+func (p *ioProgress) reserve(n int64) time.Duration {
+	p.mu.Lock()
+	l := p.limiter
+	p.mu.Unlock()
+	if l == nil || n <= 0 {
+		return 0
+	}
+	wait := l.reserve(n, p.clock.Now())
+	p.mu.Lock()
+	p.throttled = wait > 0
+	p.mu.Unlock()
+	return wait
+}
+
+// sleep waits for the duration returned by reserve, using the Clock if it is a
+// TimerClock. If the context the transfer is bound to is done while waiting,
+// the transfer is stopped and the context's error is returned.
+func (p *ioProgress) sleep(wait time.Duration) error {
+	if wait <= 0 {
+		return nil
+	}
+	var elapsed <-chan time.Time
+	if tc, ok := p.clock.(TimerClock); ok {
+		elapsed = tc.After(wait)
+	} else {
+		t := time.NewTimer(wait)
+		defer t.Stop()
+		elapsed = t.C
+	}
+	var done <-chan struct{}
+	if p.ctx != nil {
+		done = p.ctx.Done()
+	}
+	select {
+	case <-elapsed:
+		return nil
+	case <-done:
+		return p.ctxErr()
+	}
+}
+
+// ctxErr returns the error of the context the transfer is bound to, if any.
+// Once the context is done, the transfer is stopped with that error as reason.
+func (p *ioProgress) ctxErr() error {
+	if p.ctx == nil {
+		return nil
+	}
+	err := p.ctx.Err()
+	if err != nil {
+		p.stopProgress(err)
+	}
+	return err
 }
@@ -1,8 +1,16 @@
 package progressio
 
 import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/bartmeuris/progressio/progressiotest"
 )
 
 func TestPrintNoSize(t *testing.T) {
@@ -83,14 +91,344 @@ func TestPrintSize(t *testing.T) {
 }
 
 func TestIOProgress(t *testing.T) {
-	iop := mkIoProgress(100 * MebiByte)
+	ch := make(chan Progress)
+	iop := mkIoProgress(100*MebiByte, WithChannel(ch))
 	iop.progress = 50 * MebiByte
-	iop.updatesW[1] = 40 * MebiByte
-	iop.updatesT[1] = time.Now().Add(time.Second * -1)
+	iop.samples = []sample{{time.Now().Add(time.Second * -1), 40 * MebiByte}}
 	iop.startTime = time.Now().Add(time.Second * -10)
 	go iop.updateProgress(0)
-	p := <-iop.ch
+	p := <-ch
 	t.Logf("P: %p\n", &p)
 	t.Logf("P: %s\n", p.String())
 	//t.Fail()
 }
+
+func TestReaderContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r, ch := NewProgressReaderContext(ctx, strings.NewReader("some data"), 9)
+	defer r.Close()
+	b := make([]byte, 4)
+	if _, err := r.Read(b); err != nil {
+		t.Fatalf("Read before cancel failed: %v", err)
+	}
+	cancel()
+	done := make(chan Progress)
+	go func() {
+		last := Progress{}
+		for p := range ch {
+			last = p
+		}
+		done <- last
+	}()
+	if _, err := r.Read(b); err != context.Canceled {
+		t.Errorf("Read after cancel: got error %v, expected %v", err, context.Canceled)
+	}
+	last := <-done
+	if last.Err != context.Canceled {
+		t.Errorf("Final progress: got Err %v, expected %v", last.Err, context.Canceled)
+	}
+	if last.StopTime.IsZero() {
+		t.Errorf("Final progress: StopTime not set")
+	}
+	if last.Transferred != 4 {
+		t.Errorf("Final progress: got Transferred %d, expected 4", last.Transferred)
+	}
+}
+
+func TestWriterContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w, ch := NewProgressWriterContext(ctx, ioutil.Discard, -1)
+	defer w.Close()
+	go func() {
+		for range ch {
+		}
+	}()
+	if n, err := w.Write([]byte("data")); n != 0 || err != context.Canceled {
+		t.Errorf("Write after cancel: got (%d, %v), expected (0, %v)", n, err, context.Canceled)
+	}
+}
+
+func TestWriterConcurrent(t *testing.T) {
+	const workers = 8
+	const writes = 1000
+	w, ch := NewProgressWriter(ioutil.Discard, workers*writes)
+	done := make(chan Progress)
+	go func() {
+		last := Progress{}
+		for p := range ch {
+			last = p
+		}
+		done <- last
+	}()
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < writes; j++ {
+				w.Write([]byte{0})
+			}
+		}()
+	}
+	wg.Wait()
+	w.Close()
+	last := <-done
+	if last.Transferred != workers*writes {
+		t.Errorf("Final progress: got Transferred %d, expected %d", last.Transferred, workers*writes)
+	}
+	if last.Percent != 100 {
+		t.Errorf("Final progress: got Percent %.2f, expected 100", last.Percent)
+	}
+}
+
+// zeroReader is an endless io.Reader that is safe for concurrent use
+type zeroReader struct{}
+
+func (zeroReader) Read(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func TestReaderConcurrentClose(t *testing.T) {
+	r, ch := NewProgressReader(zeroReader{}, -1)
+	go func() {
+		for range ch {
+		}
+	}()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b := make([]byte, 10)
+			for j := 0; j < 1000; j++ {
+				r.Read(b)
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		r.Close()
+	}()
+	wg.Wait()
+	// Closing again and reading after closing must not panic
+	r.Close()
+	r.Read(make([]byte, 10))
+}
+
+func TestFinalUpdateUnlocked(t *testing.T) {
+	w, ch := NewProgressWriter(ioutil.Discard, 100)
+	w.Write(make([]byte, 50))
+	closed := make(chan struct{})
+	go func() {
+		// Blocks until the final update is received
+		w.Close()
+		close(closed)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	done := make(chan Progress)
+	go func() {
+		// Calling the wrapper before receiving the final update must not block
+		w.Paused()
+		w.Pause()
+		w.SetRateLimit(1000, 100)
+		last := Progress{}
+		for p := range ch {
+			last = p
+		}
+		done <- last
+	}()
+	select {
+	case last := <-done:
+		if last.StopTime.IsZero() || last.Transferred != 50 {
+			t.Errorf("Got final progress %+v, expected the transfer to be stopped at 50 bytes", last)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Deadlock: the consumer could not call the wrapper before the final update")
+	}
+	<-closed
+}
+
+func TestUpdateProgress(t *testing.T) {
+	type step struct {
+		advance time.Duration
+		written int64
+		sent    bool  // if an update is expected
+		speed   int64 // expected values of the update
+		avg     int64
+		remain  time.Duration
+		percent float64
+	}
+	tests := []struct {
+		name  string
+		size  int64
+		steps []step
+	}{
+		{
+			name: "known size",
+			size: 1000,
+			steps: []step{
+				{0, 100, true, -1, -1, -1, 10},
+				{50 * time.Millisecond, 100, false, 0, 0, 0, 0},
+				{50 * time.Millisecond, 100, true, 2000, 3000, 233333333, 30},
+				{900 * time.Millisecond, 200, true, 400, 500, time.Second, 50},
+				{time.Second, 500, true, 450, 500, 0, 100},
+			},
+		},
+		{
+			name: "unknown size",
+			size: -1,
+			steps: []step{
+				{0, 100, true, -1, -1, -1, 0},
+				{time.Millisecond, 100, false, 0, 0, 0, 0},
+				{time.Second, 100, true, 199, 299, -1, 0},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := progressiotest.NewClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+			var got []Progress
+			iop := mkIoProgress(tt.size, WithClock(clock), WithCallback(func(p Progress) { got = append(got, p) }))
+			for i, s := range tt.steps {
+				got = nil
+				clock.Advance(s.advance)
+				iop.updateProgress(s.written)
+				if !s.sent {
+					if len(got) != 0 {
+						t.Errorf("step %d: got an update, expected it to be throttled", i)
+					}
+					continue
+				}
+				if len(got) != 1 {
+					t.Fatalf("step %d: got %d updates, expected 1", i, len(got))
+				}
+				p := got[0]
+				if p.Speed != s.speed || p.SpeedAvg != s.avg || p.Remaining != s.remain || p.Percent != s.percent {
+					t.Errorf("step %d: got speed %d, avg %d, remaining %v, percent %.2f, expected %d, %d, %v, %.2f",
+						i, p.Speed, p.SpeedAvg, p.Remaining, p.Percent, s.speed, s.avg, s.remain, s.percent)
+				}
+				if p.Elapsed() != clock.Now().Sub(p.StartTime) {
+					t.Errorf("step %d: got elapsed %v, expected %v", i, p.Elapsed(), clock.Now().Sub(p.StartTime))
+				}
+			}
+		})
+	}
+}
+
+// failingIO is an io.Reader and io.Writer which fails after n bytes
+type failingIO struct {
+	n   int
+	err error
+}
+
+func (f *failingIO) Read(b []byte) (int, error) {
+	if f.n <= 0 {
+		return 0, f.err
+	}
+	if len(b) > f.n {
+		b = b[:f.n]
+	}
+	f.n -= len(b)
+	return len(b), nil
+}
+
+func (f *failingIO) Write(b []byte) (int, error) {
+	if len(b) > f.n {
+		n := f.n
+		f.n = 0
+		return n, f.err
+	}
+	f.n -= len(b)
+	return len(b), nil
+}
+
+func TestTransferOutcome(t *testing.T) {
+	broken := errors.New("broken pipe")
+	tests := []struct {
+		name      string
+		transfer  func() (io.Closer, <-chan Progress, func() error, func() error)
+		wantErr   error
+		wantState State
+	}{
+		{
+			name: "reader EOF",
+			transfer: func() (io.Closer, <-chan Progress, func() error, func() error) {
+				r, ch := NewProgressReader(strings.NewReader("some data"), -1)
+				copy := func() error { _, err := io.Copy(ioutil.Discard, r); return err }
+				return r, ch, copy, r.Wait
+			},
+			wantState: StateDone,
+		},
+		{
+			name: "reader failure",
+			transfer: func() (io.Closer, <-chan Progress, func() error, func() error) {
+				r, ch := NewProgressReader(&failingIO{5, broken}, 10)
+				copy := func() error { _, err := io.Copy(ioutil.Discard, r); return err }
+				return r, ch, copy, r.Wait
+			},
+			wantErr:   broken,
+			wantState: StateFailed,
+		},
+		{
+			name: "writer failure",
+			transfer: func() (io.Closer, <-chan Progress, func() error, func() error) {
+				w, ch := NewProgressWriter(&failingIO{5, broken}, -1)
+				copy := func() error { _, err := io.Copy(w, strings.NewReader("some data")); return err }
+				return w, ch, copy, w.Wait
+			},
+			wantErr:   broken,
+			wantState: StateFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, ch, copy, wait := tt.transfer()
+			final := make(chan Progress)
+			go func() {
+				var last Progress
+				for p := range ch {
+					last = p
+				}
+				final <- last
+			}()
+			if err := copy(); err != tt.wantErr {
+				t.Errorf("Copy error = %v, want %v", err, tt.wantErr)
+			}
+			// The final update is sent without closing the wrapper
+			p := <-final
+			if p.Err != tt.wantErr || p.State != tt.wantState {
+				t.Errorf("Final progress: got err %v, state %v, want %v, %v", p.Err, p.State, tt.wantErr, tt.wantState)
+			}
+			if err := wait(); err != tt.wantErr {
+				t.Errorf("Wait() = %v, want %v", err, tt.wantErr)
+			}
+			c.Close()
+			if err := wait(); err != tt.wantErr {
+				t.Errorf("Wait() after Close = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTransferIncomplete(t *testing.T) {
+	var last Progress
+	w := NewProgressWriterWithOptions(ioutil.Discard, 100, WithCallback(func(p Progress) { last = p }))
+	w.Write(make([]byte, 50))
+	w.Close()
+	if err := w.Wait(); err != ErrIncomplete || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Wait() after closing at 50%% = %v, want %v", err, ErrIncomplete)
+	}
+	if last.State != StateFailed || last.Err != ErrIncomplete {
+		t.Errorf("Final progress: got state %v, err %v, want %v, %v", last.State, last.Err, StateFailed, ErrIncomplete)
+	}
+
+	// A reader reaching EOF before the size is incomplete too
+	r := NewProgressReaderWithOptions(strings.NewReader("some data"), 20)
+	io.Copy(ioutil.Discard, r)
+	if err := r.Wait(); err != ErrIncomplete {
+		t.Errorf("Wait() after EOF at 9 of 20 bytes = %v, want %v", err, ErrIncomplete)
+	}
+}
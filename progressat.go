@@ -0,0 +1,100 @@
+package progressio
+
+import "io"
+
+// ProgressReaderAt is a struct representing an io.ReaderAt, which sends back progress
+// feedback like a ProgressReader. The progress counts every byte only once, no matter
+// how often and in which order the ranges are read, so it can be read from several
+// goroutines at once, e.g. to upload a file in parallel parts with retries.
+type ProgressReaderAt struct {
+	r io.ReaderAt
+	*ioProgress
+}
+
+// NewProgressReaderAt creates a new ProgressReaderAt object based on the io.ReaderAt
+// and the size you specified, configured by the options. The transfer completes once
+// all bytes up to the size were read. Specify a size <= 0 if you don't know the size.
+func NewProgressReaderAt(r io.ReaderAt, size int64, opts ...Option) *ProgressReaderAt {
+	if r == nil {
+		return nil
+	}
+	return &ProgressReaderAt{r, mkIoProgress(size, opts...)}
+}
+
+// ReadAt wraps the io.ReaderAt ReadAt function to also update the progress.
+func (p *ProgressReaderAt) ReadAt(b []byte, off int64) (int, error) {
+	return p.readAt(p.r, b, off)
+}
+
+// Covered returns the ranges read so far, sorted and merged.
+func (p *ProgressReaderAt) Covered() []Range {
+	covered, _ := p.coverage()
+	return covered
+}
+
+// Holes returns the ranges which were not read yet. If the size is unknown, only
+// the holes in between the ranges read are returned.
+func (p *ProgressReaderAt) Holes() []Range {
+	_, holes := p.coverage()
+	return holes
+}
+
+// Close closes the wrapped io.ReaderAt if it is an io.Closer, and sends the final
+// update. ProgressReaderAt objects should always be closed to make sure everything
+// is cleaned up.
+func (p *ProgressReaderAt) Close() (err error) {
+	if c, ok := p.r.(io.Closer); ok {
+		err = c.Close()
+	}
+	p.stopProgress(err)
+	return
+}
+
+// ProgressWriterAt is a struct representing an io.WriterAt, which sends back progress
+// feedback like a ProgressWriter. The progress counts every byte only once, so
+// segmented downloads written from several goroutines at once report accurate
+// progress, even when segments are retried or overlap.
+type ProgressWriterAt struct {
+	w io.WriterAt
+	*ioProgress
+}
+
+// NewProgressWriterAt creates a new ProgressWriterAt object based on the io.WriterAt
+// and the size you specified, configured by the options. The transfer completes once
+// all bytes up to the size were written. Specify a size <= 0 if you don't know the size.
+func NewProgressWriterAt(w io.WriterAt, size int64, opts ...Option) *ProgressWriterAt {
+	if w == nil {
+		return nil
+	}
+	return &ProgressWriterAt{w, mkIoProgress(size, opts...)}
+}
+
+// WriteAt wraps the io.WriterAt WriteAt function to also update the progress.
+func (p *ProgressWriterAt) WriteAt(b []byte, off int64) (int, error) {
+	return p.writeAt(p.w, b, off)
+}
+
+// Covered returns the ranges written so far, sorted and merged.
+func (p *ProgressWriterAt) Covered() []Range {
+	covered, _ := p.coverage()
+	return covered
+}
+
+// Holes returns the ranges which were not written yet, e.g. to know which
+// segments to download when resuming. If the size is unknown, only the holes in
+// between the ranges written are returned.
+func (p *ProgressWriterAt) Holes() []Range {
+	_, holes := p.coverage()
+	return holes
+}
+
+// Close closes the wrapped io.WriterAt if it is an io.Closer, and sends the final
+// update. ProgressWriterAt objects should always be closed to make sure everything
+// is cleaned up.
+func (p *ProgressWriterAt) Close() (err error) {
+	if c, ok := p.w.(io.Closer); ok {
+		err = c.Close()
+	}
+	p.stopProgress(err)
+	return
+}
@@ -0,0 +1,87 @@
+package progressio
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// memFile is an io.WriterAt writing to memory, safe for concurrent use
+type memFile struct {
+	mu   sync.Mutex
+	data []byte
+}
+
+func (m *memFile) WriteAt(b []byte, off int64) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return copy(m.data[off:], b), nil
+}
+
+func TestProgressWriterAt(t *testing.T) {
+	f := &memFile{data: make([]byte, 100)}
+	var mu sync.Mutex
+	var last Progress
+	w := NewProgressWriterAt(f, 100,
+		WithUpdateInterval(0),
+		WithCallback(func(p Progress) {
+			mu.Lock()
+			last = p
+			mu.Unlock()
+		}))
+
+	// Overlapping and retried segments, written in parallel
+	segments := []Range{{0, 20}, {10, 30}, {0, 20}, {50, 60}, {55, 70}}
+	var wg sync.WaitGroup
+	for _, s := range segments {
+		wg.Add(1)
+		go func(s Range) {
+			defer wg.Done()
+			w.WriteAt(make([]byte, s.Len()), s.Start)
+		}(s)
+	}
+	wg.Wait()
+
+	mu.Lock()
+	p := last
+	mu.Unlock()
+	if p.Transferred != 50 || p.Percent != 50 {
+		t.Errorf("Transferred %d (%.2f%%), want 50 (50%%)", p.Transferred, p.Percent)
+	}
+	if got, want := w.Covered(), []Range{{0, 30}, {50, 70}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Covered() = %v, want %v", got, want)
+	}
+	if got, want := w.Holes(), []Range{{30, 50}, {70, 100}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Holes() = %v, want %v", got, want)
+	}
+
+	// Filling the holes completes the transfer
+	for _, h := range w.Holes() {
+		w.WriteAt(make([]byte, h.Len()), h.Start)
+	}
+	if err := w.Wait(); err != nil {
+		t.Errorf("Wait() = %v", err)
+	}
+	if last.Transferred != 100 || last.State != StateDone || len(w.Holes()) != 0 {
+		t.Errorf("After filling the holes: %+v, holes %v", last, w.Holes())
+	}
+	w.Close()
+}
+
+func TestProgressReaderAt(t *testing.T) {
+	var last Progress
+	r := NewProgressReaderAt(strings.NewReader("0123456789"), -1,
+		WithCallback(func(p Progress) { last = p }))
+	b := make([]byte, 3)
+	r.ReadAt(b, 7)
+	r.ReadAt(b, 8) // reads 2 bytes, already read
+	r.ReadAt(b, 5) // 2 new bytes
+	if got, want := r.Holes(), []Range{{0, 5}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Holes() = %v, want %v", got, want)
+	}
+	r.Close()
+	if last.Transferred != 5 || last.State != StateDone {
+		t.Errorf("Final progress %+v, want 5 bytes, done", last)
+	}
+}
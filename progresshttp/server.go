@@ -0,0 +1,314 @@
+package progresshttp
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bartmeuris/progressio"
+)
+
+// RequestIDHeader is the header holding the ID of a request. If a request does
+// not have one, the Middleware generates it, and sets it on the response.
+const RequestIDHeader = "X-Request-Id"
+
+// RequestID returns the ID of the request the Middleware assigned to the
+// context, or "" if there is none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// Transfer is the progress of the bodies of a request handled by the
+// Middleware of a Registry.
+type Transfer struct {
+	ID        string              `json:"id"`
+	Method    string              `json:"method"`
+	URL       string              `json:"url"`
+	StartTime time.Time           `json:"start_time"`
+	Upload    progressio.Progress `json:"upload"`   // The progress of the request body
+	Download  progressio.Progress `json:"download"` // The progress of the response body
+}
+
+// Registry keeps the live progress of the requests in flight handled by its
+// Middleware. It is an http.Handler itself, serving the transfers in flight
+// as JSON, e.g. to monitor a server. The zero value is ready to use.
+type Registry struct {
+	// Options returns the options of the wrapper of the request body if upload
+	// is set, of the response body otherwise, e.g. to report the progress to a
+	// metrics.Collector. It is called for every wrapper, so options which can
+	// not be shared, like WithChannel and WithEstimator, have to be created
+	// by it. Nil means no extra options.
+	Options func(r *http.Request, upload bool) []progressio.Option
+
+	mu        sync.Mutex
+	transfers map[string]*Transfer
+}
+
+// NewRegistry creates a new, empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{transfers: make(map[string]*Transfer)}
+}
+
+// Transfers returns the progress of the requests in flight, in the order they
+// were started.
+func (reg *Registry) Transfers() []Transfer {
+	reg.mu.Lock()
+	ret := make([]Transfer, 0, len(reg.transfers))
+	for _, t := range reg.transfers {
+		ret = append(ret, *t)
+	}
+	reg.mu.Unlock()
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].StartTime.Equal(ret[j].StartTime) {
+			return ret[i].ID < ret[j].ID
+		}
+		return ret[i].StartTime.Before(ret[j].StartTime)
+	})
+	return ret
+}
+
+// Get returns the progress of the request in flight with the ID.
+func (reg *Registry) Get(id string) (Transfer, bool) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	t, ok := reg.transfers[id]
+	if !ok {
+		return Transfer{}, false
+	}
+	return *t, true
+}
+
+// Middleware wraps the request body and the ResponseWriter to track their
+// progress in the registry while the request is handled. The wrapped
+// ResponseWriter implements http.Flusher, http.Hijacker and io.ReaderFrom if
+// the original one does, and can be unwrapped by http.ResponseController. The
+// size of the response is taken from its Content-Length header, which has to
+// be set before writing the body. Responses without body, to HEAD requests or
+// with the 204 or 304 status, have an unknown size.
+func (reg *Registry) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t := reg.add(r)
+		defer reg.remove(t.ID)
+		w.Header().Set(RequestIDHeader, t.ID)
+		r = r.WithContext(context.WithValue(r.Context(), requestIDKey, t.ID))
+
+		var upload *progressio.ProgressReader
+		if r.Body != nil && r.Body != http.NoBody {
+			upload = progressio.NewProgressReaderWithOptions(r.Body, r.ContentLength, reg.options(r, t.ID, true)...)
+			r.Body = upload
+		}
+		rw := &responseWriter{ResponseWriter: w, head: r.Method == http.MethodHead, opts: reg.options(r, t.ID, false)}
+
+		defer func() {
+			if upload != nil {
+				upload.Close()
+			}
+			rw.close()
+		}()
+		next.ServeHTTP(rw.wrap(), r)
+	})
+}
+
+// options returns the options of a new wrapper of a body of the request,
+// reporting its progress to the transfer with the ID.
+func (reg *Registry) options(r *http.Request, id string, upload bool) []progressio.Option {
+	opts := []progressio.Option{progressio.WithContext(r.Context())}
+	if reg.Options != nil {
+		opts = append(opts, reg.Options(r, upload)...)
+	}
+	return append(opts, progressio.WithCallback(func(p progressio.Progress) {
+		reg.update(id, func(t *Transfer) {
+			if upload {
+				t.Upload = p
+			} else {
+				t.Download = p
+			}
+		})
+	}))
+}
+
+// ServeHTTP serves the transfers in flight as a JSON array.
+func (reg *Registry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reg.Transfers())
+}
+
+// add registers a new transfer for the request, using its request ID if it is
+// not in use.
+func (reg *Registry) add(r *http.Request) *Transfer {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if reg.transfers == nil {
+		reg.transfers = make(map[string]*Transfer)
+	}
+	id := r.Header.Get(RequestIDHeader)
+	for id == "" || reg.transfers[id] != nil {
+		id = newRequestID()
+	}
+	t := &Transfer{
+		ID:        id,
+		Method:    r.Method,
+		URL:       r.URL.String(),
+		StartTime: time.Now(),
+		Upload:    progressio.Progress{TotalSize: r.ContentLength, Speed: -1, SpeedAvg: -1, Remaining: -1},
+		Download:  progressio.Progress{TotalSize: -1, Speed: -1, SpeedAvg: -1, Remaining: -1},
+	}
+	reg.transfers[id] = t
+	return t
+}
+
+func (reg *Registry) update(id string, fn func(*Transfer)) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if t, ok := reg.transfers[id]; ok {
+		fn(t)
+	}
+}
+
+func (reg *Registry) remove(id string) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	delete(reg.transfers, id)
+}
+
+// newRequestID generates a random request ID.
+func newRequestID() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// responseWriter tracks the progress of the response body. The ProgressWriter
+// is created on the first write, once the Content-Length header is known.
+type responseWriter struct {
+	http.ResponseWriter
+	opts []progressio.Option
+	head bool // the response to a HEAD request has no body
+
+	mu     sync.Mutex
+	status int
+	pw     *progressio.ProgressWriter
+}
+
+func (w *responseWriter) download() *progressio.ProgressWriter {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.pw == nil {
+		size, err := strconv.ParseInt(w.Header().Get("Content-Length"), 10, 64)
+		if err != nil || w.head || w.status == http.StatusNoContent || w.status == http.StatusNotModified {
+			size = -1
+		}
+		w.pw = progressio.NewProgressWriterWithOptions(w.ResponseWriter, size, w.opts...)
+	}
+	return w.pw
+}
+
+// close sends the final update of the response body.
+func (w *responseWriter) close() {
+	w.download().Close()
+}
+
+// WriteHeader records the status, to know if the response has a body.
+func (w *responseWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	if w.status == 0 && code >= 200 {
+		w.status = code
+	}
+	w.mu.Unlock()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	return w.download().Write(b)
+}
+
+// ReadFrom uses the io.ReaderFrom of the original ResponseWriter, e.g. to use
+// sendfile, while tracking the progress.
+func (w *responseWriter) ReadFrom(r io.Reader) (int64, error) {
+	return w.download().ReadFrom(r)
+}
+
+func (w *responseWriter) Flush() {
+	w.ResponseWriter.(http.Flusher).Flush()
+}
+
+// Hijack takes over the connection, which ends the response body.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := w.ResponseWriter.(http.Hijacker).Hijack()
+	if err == nil {
+		w.close()
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the original ResponseWriter, for http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// wrap returns w implementing only the optional interfaces the original
+// ResponseWriter implements.
+func (w *responseWriter) wrap() http.ResponseWriter {
+	_, flusher := w.ResponseWriter.(http.Flusher)
+	_, hijacker := w.ResponseWriter.(http.Hijacker)
+	_, readerFrom := w.ResponseWriter.(io.ReaderFrom)
+	type unwrapper interface {
+		http.ResponseWriter
+		Unwrap() http.ResponseWriter
+	}
+	switch {
+	case flusher && hijacker && readerFrom:
+		return struct {
+			unwrapper
+			http.Flusher
+			http.Hijacker
+			io.ReaderFrom
+		}{w, w, w, w}
+	case flusher && hijacker:
+		return struct {
+			unwrapper
+			http.Flusher
+			http.Hijacker
+		}{w, w, w}
+	case flusher && readerFrom:
+		return struct {
+			unwrapper
+			http.Flusher
+			io.ReaderFrom
+		}{w, w, w}
+	case hijacker && readerFrom:
+		return struct {
+			unwrapper
+			http.Hijacker
+			io.ReaderFrom
+		}{w, w, w}
+	case flusher:
+		return struct {
+			unwrapper
+			http.Flusher
+		}{w, w}
+	case hijacker:
+		return struct {
+			unwrapper
+			http.Hijacker
+		}{w, w}
+	case readerFrom:
+		return struct {
+			unwrapper
+			io.ReaderFrom
+		}{w, w}
+	}
+	return struct{ unwrapper }{w}
+}
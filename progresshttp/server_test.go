@@ -0,0 +1,188 @@
+package progresshttp
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bartmeuris/progressio"
+)
+
+func TestMiddleware(t *testing.T) {
+	reg := NewRegistry()
+	halfway := make(chan string)
+	proceed := make(chan struct{})
+	handler := reg.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Content-Length", "1000")
+		w.Write(body[:400])
+		halfway <- RequestID(r.Context())
+		<-proceed
+		w.Write(make([]byte, 600))
+	}))
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	done := make(chan *http.Response)
+	go func() {
+		req, _ := http.NewRequest("POST", srv.URL+"/upload?x=1", strings.NewReader(strings.Repeat("x", 500)))
+		req.Header.Set(RequestIDHeader, "my-request")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Error(err)
+			close(done)
+			return
+		}
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+		done <- resp
+	}()
+
+	if id := <-halfway; id != "my-request" {
+		t.Errorf("RequestID() = %q, want my-request", id)
+	}
+	rec := httptest.NewRecorder()
+	reg.ServeHTTP(rec, httptest.NewRequest("GET", "/transfers", nil))
+	var transfers []struct {
+		ID     string `json:"id"`
+		Method string `json:"method"`
+		URL    string `json:"url"`
+		Upload struct {
+			Transferred int64 `json:"transferred"`
+			TotalSize   int64 `json:"total_size"`
+		}
+		Download struct {
+			Transferred int64   `json:"transferred"`
+			TotalSize   int64   `json:"total_size"`
+			Percent     float64 `json:"percent"`
+			State       string  `json:"state"`
+		}
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &transfers); err != nil {
+		t.Fatalf("Invalid JSON %q: %v", rec.Body.String(), err)
+	}
+	if len(transfers) != 1 {
+		t.Fatalf("Got %d transfers, want 1: %s", len(transfers), rec.Body.String())
+	}
+	tr := transfers[0]
+	if tr.ID != "my-request" || tr.Method != "POST" || tr.URL != "/upload?x=1" {
+		t.Errorf("Got transfer %+v", tr)
+	}
+	if tr.Upload.Transferred != 500 || tr.Upload.TotalSize != 500 || tr.Download.Transferred != 400 || tr.Download.TotalSize != 1000 ||
+		tr.Download.Percent != 40 || tr.Download.State != "running" {
+		t.Errorf("Got progress %+v", tr)
+	}
+
+	close(proceed)
+	if resp := <-done; resp != nil && resp.Header.Get(RequestIDHeader) != "my-request" {
+		t.Errorf("Response request ID %q", resp.Header.Get(RequestIDHeader))
+	}
+	if n := len(reg.Transfers()); n != 0 {
+		t.Errorf("%d transfers left after the request", n)
+	}
+}
+
+func TestMiddlewareInterfaces(t *testing.T) {
+	var reg Registry
+	check := func(flusher, hijacker, readerFrom bool) http.Handler {
+		return reg.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := w.(http.Flusher); ok != flusher {
+				t.Errorf("http.Flusher: %v, want %v", ok, flusher)
+			}
+			if _, ok := w.(http.Hijacker); ok != hijacker {
+				t.Errorf("http.Hijacker: %v, want %v", ok, hijacker)
+			}
+			if _, ok := w.(io.ReaderFrom); ok != readerFrom {
+				t.Errorf("io.ReaderFrom: %v, want %v", ok, readerFrom)
+			}
+			if err := http.NewResponseController(w).Flush(); (err == nil) != flusher {
+				t.Errorf("ResponseController.Flush() = %v", err)
+			}
+		}))
+	}
+
+	// The ResponseRecorder only implements http.Flusher
+	check(true, false, false).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	srv := httptest.NewServer(check(true, true, true))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+}
+
+func TestMiddlewareOutcome(t *testing.T) {
+	var last progressio.Progress
+	reg := NewRegistry()
+	reg.Options = func(*http.Request, bool) []progressio.Option {
+		return []progressio.Option{progressio.WithCallback(func(p progressio.Progress) { last = p })}
+	}
+	handler := reg.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		if r.Method == "GET" {
+			w.Write(make([]byte, 100))
+		}
+	}))
+	tests := []struct {
+		method string
+		want   progressio.State
+	}{
+		{"GET", progressio.StateDone},
+		{"HEAD", progressio.StateDone},   // the response has no body
+		{"POST", progressio.StateFailed}, // the body was not written
+	}
+	for _, tt := range tests {
+		last = progressio.Progress{}
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, "/", nil))
+		if last.State != tt.want {
+			t.Errorf("%s: got state %v (%v), want %v", tt.method, last.State, last.Err, tt.want)
+		}
+	}
+}
+
+func TestMiddlewareChannelOption(t *testing.T) {
+	var mu sync.Mutex // the channels of the upload and download are read concurrently
+	var wg sync.WaitGroup
+	var finals []progressio.Progress
+	reg := NewRegistry()
+	// Every wrapper gets its own channel
+	reg.Options = func(r *http.Request, upload bool) []progressio.Option {
+		ch := make(chan progressio.Progress)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var last progressio.Progress
+			for p := range ch {
+				last = p
+			}
+			mu.Lock()
+			finals = append(finals, last)
+			mu.Unlock()
+		}()
+		return []progressio.Option{progressio.WithChannel(ch), progressio.WithGuaranteedFinal()}
+	}
+	handler := reg.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(ioutil.Discard, r.Body)
+		w.Header().Set("Content-Length", "10")
+		w.Write(make([]byte, 10))
+	}))
+	for i := 0; i < 2; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/", strings.NewReader("some data")))
+	}
+	wg.Wait()
+	if len(finals) != 4 {
+		t.Fatalf("Got %d final updates, want 4", len(finals))
+	}
+	for _, p := range finals {
+		if p.State != progressio.StateDone {
+			t.Errorf("Got final progress %+v", p)
+		}
+	}
+}
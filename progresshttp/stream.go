@@ -0,0 +1,158 @@
+package progresshttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bartmeuris/progressio"
+)
+
+// DefaultHeartbeat is the time without updates after which a Stream sends a
+// heartbeat event
+const DefaultHeartbeat = 15 * time.Second
+
+// The events sent by a Stream
+const (
+	EventProgress  = "progress"  // An update, the data is the Progress
+	EventHeartbeat = "heartbeat" // No update was received for a while, the data holds the idle time
+	EventDone      = "done"      // The transfer is finished, the data is the summary
+)
+
+// Stream is an http.Handler streaming the updates received over a channel to
+// the client, e.g. to show the progress of a transfer in a browser. The
+// updates are sent as Server-Sent Events, or as newline-delimited JSON if the
+// client accepts "application/x-ndjson" and not "text/event-stream".
+//
+// Every update is sent as a "progress" event with the Progress as JSON. If no
+// update is received for the Heartbeat duration, a "heartbeat" event is sent,
+// so clients can tell a stalled transfer from a broken connection. Once the
+// channel is closed, a final "done" event is sent with the summary of the
+// transfer: the transferred, total_size, speed_avg, elapsed, state and error
+// fields of the last update, with their "_human" counterparts, in the same
+// format. Its data is null if the channel was closed without any update. With
+// newline-delimited JSON, every line is an object holding the event name and
+// its data:
+//
+//	{"event":"progress","data":{"transferred":1024,...}}
+//
+// A Stream serves a single request. If the client goes away, the channel is
+// drained, so the transfer never blocks on it.
+type Stream struct {
+	Updates   <-chan progressio.Progress // The channel the updates are received over
+	Heartbeat time.Duration              // DefaultHeartbeat if 0
+}
+
+// summaryKeys are the fields of the JSON format of the Progress kept in the
+// data of the "done" event
+var summaryKeys = []string{
+	"transferred", "transferred_human",
+	"total_size", "total_size_human",
+	"speed_avg", "speed_avg_human",
+	"elapsed", "elapsed_human",
+	"state", "error",
+}
+
+// ServeHTTP streams the updates until the channel is closed or the client goes
+// away.
+func (s *Stream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	accept := r.Header.Get("Accept")
+	ndjson := strings.Contains(accept, "application/x-ndjson") && !strings.Contains(accept, "text/event-stream")
+	if ndjson {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	} else {
+		w.Header().Set("Content-Type", "text/event-stream")
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	rc := http.NewResponseController(w)
+
+	send := func(event string, data interface{}) error {
+		b, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		if ndjson {
+			_, err = fmt.Fprintf(w, "{\"event\":%q,\"data\":%s}\n", event, b)
+		} else {
+			_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, b)
+		}
+		if err != nil {
+			return err
+		}
+		rc.Flush()
+		return nil
+	}
+
+	heartbeat := s.Heartbeat
+	if heartbeat <= 0 {
+		heartbeat = DefaultHeartbeat
+	}
+	t := time.NewTimer(heartbeat)
+	defer t.Stop()
+	var last progressio.Progress
+	var received bool
+	lastUpdate := time.Now()
+	for {
+		var err error
+		select {
+		case p, ok := <-s.Updates:
+			if !ok {
+				var summary map[string]json.RawMessage // null without updates
+				if received {
+					if summary, err = summarize(last); err != nil {
+						return
+					}
+				}
+				send(EventDone, summary)
+				return
+			}
+			last, lastUpdate, received = p, time.Now(), true
+			err = send(EventProgress, p)
+			if !t.Stop() {
+				select {
+				case <-t.C:
+				default:
+				}
+			}
+			t.Reset(heartbeat)
+		case now := <-t.C:
+			idle := now.Sub(lastUpdate).Round(time.Second)
+			err = send(EventHeartbeat, map[string]float64{"idle": idle.Seconds()})
+			t.Reset(heartbeat)
+		case <-r.Context().Done():
+			err = r.Context().Err()
+		}
+		if err != nil {
+			go drain(s.Updates)
+			return
+		}
+	}
+}
+
+// summarize returns the summary of the transfer sent in the "done" event, taken
+// from the JSON format of its last update.
+func summarize(p progressio.Progress) (map[string]json.RawMessage, error) {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	ret := make(map[string]json.RawMessage, len(summaryKeys))
+	for _, key := range summaryKeys {
+		if v, ok := fields[key]; ok {
+			ret[key] = v
+		}
+	}
+	return ret, nil
+}
+
+func drain(ch <-chan progressio.Progress) {
+	for range ch {
+	}
+}
@@ -0,0 +1,101 @@
+package progresshttp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bartmeuris/progressio"
+)
+
+func TestStream(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept string
+		ctype  string
+	}{
+		{"SSE", "text/event-stream", "text/event-stream"},
+		{"default", "", "text/event-stream"},
+		{"NDJSON", "application/x-ndjson", "application/x-ndjson"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := make(chan progressio.Progress)
+			go func() {
+				ch <- progressio.Progress{Transferred: 10, TotalSize: 100, Percent: 10}
+				time.Sleep(50 * time.Millisecond) // stalled: heartbeat
+				ch <- progressio.Progress{Transferred: 50, TotalSize: -1, Speed: -1, SpeedAvg: -1, Remaining: -1,
+					State: progressio.StateFailed, Err: errors.New("broken")}
+				close(ch)
+			}()
+			req := httptest.NewRequest("GET", "/progress", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			(&Stream{Updates: ch, Heartbeat: 20 * time.Millisecond}).ServeHTTP(rec, req)
+
+			if ct := rec.Header().Get("Content-Type"); ct != tt.ctype {
+				t.Errorf("Content-Type %q, want %q", ct, tt.ctype)
+			}
+			var events []string
+			var data []string
+			sc := bufio.NewScanner(rec.Body)
+			for sc.Scan() {
+				line := sc.Text()
+				if tt.ctype == "application/x-ndjson" {
+					var ev struct {
+						Event string
+						Data  json.RawMessage
+					}
+					if err := json.Unmarshal([]byte(line), &ev); err != nil {
+						t.Fatalf("Invalid line %q: %v", line, err)
+					}
+					events = append(events, ev.Event)
+					data = append(data, string(ev.Data))
+				} else if strings.HasPrefix(line, "event: ") {
+					events = append(events, strings.TrimPrefix(line, "event: "))
+				} else if strings.HasPrefix(line, "data: ") {
+					data = append(data, strings.TrimPrefix(line, "data: "))
+				}
+			}
+			if len(events) < 4 || events[0] != EventProgress || events[1] != EventHeartbeat ||
+				events[len(events)-2] != EventProgress || events[len(events)-1] != EventDone {
+				t.Fatalf("Got events %v", events)
+			}
+			if !strings.Contains(data[0], `"transferred":10`) || !strings.Contains(data[0], `"state":"running"`) {
+				t.Errorf("Progress event data %s", data[0])
+			}
+			var summary map[string]interface{}
+			if err := json.Unmarshal([]byte(data[len(data)-1]), &summary); err != nil {
+				t.Fatal(err)
+			}
+			if summary["transferred"] != 50.0 || summary["transferred_human"] != "50B" ||
+				summary["state"] != "failed" || summary["error"] != "broken" {
+				t.Errorf("Summary %v", summary)
+			}
+			// Unknown values are left out, like in the progress events
+			for _, key := range []string{"total_size", "speed_avg", "elapsed"} {
+				if _, ok := summary[key]; ok {
+					t.Errorf("Summary has unknown %s: %v", key, summary)
+				}
+			}
+		})
+	}
+}
+
+func TestStreamNoUpdates(t *testing.T) {
+	ch := make(chan progressio.Progress)
+	close(ch)
+	req := httptest.NewRequest("GET", "/progress", nil)
+	rec := httptest.NewRecorder()
+	(&Stream{Updates: ch}).ServeHTTP(rec, req)
+	// Without any update there is nothing to summarize, not even the state
+	if got, want := rec.Body.String(), "event: done\ndata: null\n\n"; got != want {
+		t.Errorf("Got %q, want %q", got, want)
+	}
+}
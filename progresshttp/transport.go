@@ -0,0 +1,256 @@
+/*
+Package progresshttp reports the progress of HTTP transfers using the
+progressio package.
+
+The Transport is an http.RoundTripper which wraps the request and response
+bodies with a progressio.ProgressReader. The progress of a single request is
+received by binding options like progressio.WithCallback or
+progressio.WithChannel to the context of the request:
+
+	client := &http.Client{Transport: &progresshttp.Transport{}}
+	ch := make(chan progressio.Progress, 1)
+	ctx := progresshttp.WithDownloadProgress(req.Context(), progressio.WithChannel(ch))
+	resp, err := client.Do(req.WithContext(ctx))
+
+The progress of all requests can be followed with the OnUpload and OnDownload
+callbacks of the Transport.
+*/
+package progresshttp
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/bartmeuris/progressio"
+)
+
+type ctxKey int
+
+const (
+	uploadKey ctxKey = iota
+	downloadKey
+	requestIDKey
+)
+
+// WithUploadProgress returns a copy of ctx which makes the Transport report the
+// progress of the request body using the options, e.g. progressio.WithCallback.
+func WithUploadProgress(ctx context.Context, opts ...progressio.Option) context.Context {
+	return context.WithValue(ctx, uploadKey, opts)
+}
+
+// WithDownloadProgress returns a copy of ctx which makes the Transport report the
+// progress of the response body using the options, e.g. progressio.WithChannel.
+func WithDownloadProgress(ctx context.Context, opts ...progressio.Option) context.Context {
+	return context.WithValue(ctx, downloadKey, opts)
+}
+
+// Transport is an http.RoundTripper reporting the progress of the request and
+// response bodies. The bodies are only wrapped when their progress is
+// reported, through the context of the request or the callbacks.
+//
+// The size of the request body is its ContentLength, and the body is rewound
+// using GetBody when the request is retried: the progress moves back to the
+// start. The upload is only finished when the response body is closed, or the
+// request failed, as the body can be sent again until then. The size of the
+// response body is its Content-Length, unknown for chunked responses.
+//
+// When the Base transport is an *http.Transport which transparently
+// decompresses gzip responses, the Transport does that itself, so the progress
+// counts the compressed bytes received against the compressed size. The
+// response then looks as if it was decompressed by the Base transport.
+type Transport struct {
+	Base       http.RoundTripper                        // The transport used, http.DefaultTransport if nil
+	OnUpload   func(*http.Request, progressio.Progress) // Called with the progress of every request body
+	OnDownload func(*http.Request, progressio.Progress) // Called with the progress of every response body
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	upOpts := t.options(req, uploadKey, t.OnUpload)
+	downOpts := t.options(req, downloadKey, t.OnDownload)
+	if upOpts == nil && downOpts == nil {
+		return t.base().RoundTrip(req)
+	}
+
+	r2 := req.Clone(req.Context())
+	var upload *progressio.ProgressReader
+	if upOpts != nil && req.Body != nil && req.Body != http.NoBody {
+		upload = wrapRequestBody(r2, req, upOpts)
+	}
+	gunzip := downOpts != nil && t.decompresses(req)
+	if gunzip {
+		r2.Header.Set("Accept-Encoding", "gzip")
+	}
+
+	resp, err := t.base().RoundTrip(r2)
+	if err != nil {
+		if upload != nil {
+			upload.Close()
+		}
+		return nil, err
+	}
+	if downOpts == nil || resp.Body == nil || resp.Body == http.NoBody {
+		if upload != nil {
+			resp.Body = &responseBody{resp.Body, resp.Body, upload}
+		}
+		return resp, nil
+	}
+
+	download := progressio.NewProgressReaderWithOptions(resp.Body, resp.ContentLength, downOpts...)
+	body := &responseBody{download, download, upload}
+	if gunzip && strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		body.Reader = &gzipReader{r: download}
+		resp.Header.Del("Content-Encoding")
+		resp.Header.Del("Content-Length")
+		resp.ContentLength = -1
+		resp.Uncompressed = true
+	}
+	resp.Body = body
+	return resp, nil
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+// options returns the options to report the progress of a body of the request
+// with, nil if it is not reported.
+func (t *Transport) options(req *http.Request, key ctxKey, fn func(*http.Request, progressio.Progress)) []progressio.Option {
+	ctxOpts, _ := req.Context().Value(key).([]progressio.Option)
+	if ctxOpts == nil && fn == nil {
+		return nil
+	}
+	opts := []progressio.Option{progressio.WithContext(req.Context())}
+	if fn != nil {
+		opts = append(opts, progressio.WithCallback(func(p progressio.Progress) { fn(req, p) }))
+	}
+	return append(opts, ctxOpts...)
+}
+
+// decompresses returns if the base transport would transparently decompress
+// the response, using the same conditions as the *http.Transport.
+func (t *Transport) decompresses(req *http.Request) bool {
+	ht, ok := t.base().(*http.Transport)
+	return ok && !ht.DisableCompression &&
+		req.Header.Get("Accept-Encoding") == "" &&
+		req.Header.Get("Range") == "" &&
+		req.Method != http.MethodHead
+}
+
+// wrapRequestBody wraps the body of r2, a clone of req, with a ProgressReader.
+// If the request can be retried, GetBody rewinds the same ProgressReader, which
+// is kept open until the response body is closed.
+func wrapRequestBody(r2, req *http.Request, opts []progressio.Option) *progressio.ProgressReader {
+	size := req.ContentLength
+	if size == 0 {
+		// The length of a request body is unknown if ContentLength is 0
+		size = -1
+	}
+	body := &rewindBody{rc: req.Body, getBody: req.GetBody}
+	pr := progressio.NewProgressReaderWithOptions(body, size, append(opts, progressio.WithKeepOpen())...)
+	r2.Body = &requestBody{pr, body}
+	if req.GetBody != nil {
+		r2.GetBody = func() (io.ReadCloser, error) {
+			if _, err := pr.Seek(0, io.SeekStart); err != nil {
+				return nil, err
+			}
+			return &requestBody{pr, body}, nil
+		}
+	}
+	return pr
+}
+
+// rewindBody is a request body which can be rewound to the start using GetBody
+type rewindBody struct {
+	mu      sync.Mutex
+	rc      io.ReadCloser
+	closed  bool
+	getBody func() (io.ReadCloser, error)
+}
+
+func (b *rewindBody) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	rc := b.rc
+	b.mu.Unlock()
+	return rc.Read(p)
+}
+
+// Seek only supports rewinding to the start, by replacing the body with a new
+// one obtained from GetBody.
+func (b *rewindBody) Seek(offset int64, whence int) (int64, error) {
+	if offset != 0 || whence != io.SeekStart || b.getBody == nil {
+		return 0, errors.New("progresshttp: the request body can only be rewound to the start")
+	}
+	rc, err := b.getBody()
+	if err != nil {
+		return 0, err
+	}
+	b.Close()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rc, b.closed = rc, false
+	return 0, nil
+}
+
+// Close closes the current body, only once.
+func (b *rewindBody) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	return b.rc.Close()
+}
+
+// requestBody is the body of a single attempt to send the request: closing it
+// closes the body, but keeps the progress going for a retry.
+type requestBody struct {
+	pr   *progressio.ProgressReader
+	body *rewindBody
+}
+
+func (b *requestBody) Read(p []byte) (int, error) { return b.pr.Read(p) }
+func (b *requestBody) Close() error               { return b.body.Close() }
+
+// responseBody is the body of the response, closing it also finishes the
+// upload of the request body.
+type responseBody struct {
+	io.Reader
+	closer io.Closer
+	upload *progressio.ProgressReader
+}
+
+func (b *responseBody) Close() error {
+	err := b.closer.Close()
+	if b.upload != nil {
+		b.upload.Close()
+	}
+	return err
+}
+
+// gzipReader decompresses the response body, the gzip header is read on the
+// first Read like the *http.Transport does.
+type gzipReader struct {
+	r   io.Reader
+	zr  *gzip.Reader
+	err error
+}
+
+func (g *gzipReader) Read(p []byte) (int, error) {
+	if g.zr == nil && g.err == nil {
+		g.zr, g.err = gzip.NewReader(g.r)
+	}
+	if g.err != nil {
+		return 0, g.err
+	}
+	return g.zr.Read(p)
+}
@@ -0,0 +1,207 @@
+package progresshttp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bartmeuris/progressio"
+)
+
+// recorder records the final progress received by a callback
+type recorder struct {
+	mu    sync.Mutex
+	final progressio.Progress
+	calls int
+}
+
+func (r *recorder) option() progressio.Option {
+	return progressio.WithCallback(func(p progressio.Progress) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.calls++
+		if !p.StopTime.IsZero() {
+			r.final = p
+		}
+	})
+}
+
+func (r *recorder) get() progressio.Progress {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.final
+}
+
+func TestTransport(t *testing.T) {
+	data := strings.Repeat("0123456789", 1000)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/echo":
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+			w.Write(body)
+		case "/chunked":
+			w.Write([]byte(data[:5000]))
+			w.(http.Flusher).Flush()
+			w.Write([]byte(data[5000:]))
+		}
+	}))
+	defer srv.Close()
+	client := &http.Client{Transport: &Transport{}}
+
+	t.Run("upload and download", func(t *testing.T) {
+		var up, down recorder
+		req, _ := http.NewRequest("POST", srv.URL+"/echo", strings.NewReader(data))
+		ctx := WithUploadProgress(req.Context(), up.option())
+		ctx = WithDownloadProgress(ctx, down.option())
+		resp, err := client.Do(req.WithContext(ctx))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != data {
+			t.Errorf("Got a body of %d bytes, want %d", len(body), len(data))
+		}
+		for name, p := range map[string]progressio.Progress{"upload": up.get(), "download": down.get()} {
+			if p.Transferred != int64(len(data)) || p.TotalSize != int64(len(data)) || p.State != progressio.StateDone {
+				t.Errorf("Final %s progress: %+v", name, p)
+			}
+		}
+	})
+
+	t.Run("chunked", func(t *testing.T) {
+		var down recorder
+		req, _ := http.NewRequest("GET", srv.URL+"/chunked", nil)
+		resp, err := client.Do(req.WithContext(WithDownloadProgress(req.Context(), down.option())))
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+		if p := down.get(); p.Transferred != int64(len(data)) || p.TotalSize > 0 {
+			t.Errorf("Final download progress: %+v", p)
+		}
+	})
+
+	t.Run("callbacks", func(t *testing.T) {
+		var downloads recorder
+		client := &http.Client{Transport: &Transport{
+			OnDownload: func(req *http.Request, p progressio.Progress) {
+				if req.URL.Path != "/echo" {
+					t.Errorf("Callback called for %s", req.URL.Path)
+				}
+				downloads.mu.Lock()
+				downloads.final = p
+				downloads.mu.Unlock()
+			},
+		}}
+		resp, err := client.Post(srv.URL+"/echo", "text/plain", strings.NewReader("hello"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+		if p := downloads.get(); p.Transferred != 5 {
+			t.Errorf("Final download progress: %+v", p)
+		}
+	})
+}
+
+func TestTransportGzip(t *testing.T) {
+	data := strings.Repeat("compressible ", 10000)
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write([]byte(data))
+	zw.Close()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") != "gzip" {
+			w.Write([]byte(data))
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("Content-Length", strconv.Itoa(compressed.Len()))
+		w.Write(compressed.Bytes())
+	}))
+	defer srv.Close()
+
+	var down recorder
+	client := &http.Client{Transport: &Transport{}}
+	req, _ := http.NewRequest("GET", srv.URL, nil)
+	resp, err := client.Do(req.WithContext(WithDownloadProgress(req.Context(), down.option())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || string(body) != data {
+		t.Fatalf("Got a body of %d bytes, %v, want %d bytes", len(body), err, len(data))
+	}
+	if !resp.Uncompressed || resp.ContentLength != -1 || resp.Header.Get("Content-Encoding") != "" {
+		t.Errorf("Response does not look decompressed: %v, %d, %v", resp.Uncompressed, resp.ContentLength, resp.Header)
+	}
+	// The progress counts the compressed bytes
+	n := int64(compressed.Len())
+	if p := down.get(); p.Transferred != n || p.TotalSize != n || p.Percent != 100 {
+		t.Errorf("Final download progress: %+v, want %d bytes", p, n)
+	}
+}
+
+// retryTransport reads the first bytes of the request body, or all of it if
+// first < 0, and then retries the request using GetBody, like the
+// *http.Transport does on a broken connection.
+type retryTransport struct {
+	first       int64
+	beforeRetry func()
+	got         []byte
+}
+
+func (rt *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if rt.first < 0 {
+		io.Copy(ioutil.Discard, req.Body)
+	} else {
+		io.CopyN(ioutil.Discard, req.Body, rt.first)
+	}
+	req.Body.Close()
+	rt.beforeRetry()
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	rt.got, _ = ioutil.ReadAll(body)
+	body.Close()
+	return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+}
+
+func TestTransportRetry(t *testing.T) {
+	for _, first := range []int64{3, -1} {
+		var up recorder
+		base := &retryTransport{first: first}
+		base.beforeRetry = func() {
+			// Sending the whole body does not finish the upload, it is retried
+			if p := up.get(); !p.StopTime.IsZero() {
+				t.Errorf("First attempt of %d bytes: the upload finished before the retry: %+v", first, p)
+			}
+		}
+		client := &http.Client{Transport: &Transport{Base: base}}
+		req, _ := http.NewRequest("PUT", "http://example.com/", strings.NewReader("some data"))
+		resp, err := client.Do(req.WithContext(WithUploadProgress(req.Context(), up.option())))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if string(base.got) != "some data" {
+			t.Errorf("First attempt of %d bytes: the retry sent %q", first, base.got)
+		}
+		if p := up.get(); p.Transferred != 9 || p.State != progressio.StateDone || p.Err != nil {
+			t.Errorf("First attempt of %d bytes: final upload progress: %+v", first, p)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+/*
+Package progressiotest contains utilities to test code using the progressio
+package.
+
+The Clock type is a fake progressio.TimerClock, which only moves when told to,
+so the speed, average speed, time remaining and throttling of the progress
+updates can be tested deterministically:
+
+	clock := progressiotest.NewClock(time.Now())
+	w := progressio.NewProgressWriterWithOptions(mywriter, size,
+		progressio.WithClock(clock),
+		progressio.WithCallback(func(p progressio.Progress) { ... }),
+	)
+	w.Write(data)
+	clock.Advance(time.Second)
+	w.Write(data)
+*/
+package progressiotest
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is a fake clock, it implements the progressio.TimerClock interface. It
+// is safe for concurrent use.
+type Clock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond // signaled when a timer is added, created on first use
+	now     time.Time
+	waiters []waiter
+}
+
+// waiter is a timer created by After which did not fire yet
+type waiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+// NewClock creates a new Clock set to the specified time.
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+// Now returns the current time of the clock.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by the specified duration, firing the timers
+// which expire.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	c.fire()
+}
+
+// Set sets the clock to the specified time, firing the timers which expire.
+func (c *Clock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+	c.fire()
+}
+
+// After returns a channel on which the time of the clock is sent once it has
+// been advanced by d.
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	c.waiters = append(c.waiters, waiter{c.now.Add(d), ch})
+	c.fire()
+	c.condition().Broadcast()
+	return ch
+}
+
+// BlockUntil blocks until at least n timers created by After are waiting for
+// the clock to be advanced, e.g. until a rate limited transfer is throttled.
+func (c *Clock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.waiters) < n {
+		c.condition().Wait()
+	}
+}
+
+// condition returns the condition signaled when a timer is added, c.mu must be
+// held.
+func (c *Clock) condition() *sync.Cond {
+	if c.cond == nil {
+		c.cond = sync.NewCond(&c.mu)
+	}
+	return c.cond
+}
+
+// fire sends the time on the timers which expired, c.mu must be held.
+func (c *Clock) fire() {
+	pending := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			pending = append(pending, w)
+		} else {
+			w.ch <- c.now
+		}
+	}
+	c.waiters = pending
+}
@@ -0,0 +1,45 @@
+package progressiotest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClock(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewClock(start)
+	if got := c.Now(); !got.Equal(start) {
+		t.Errorf("Now() got %v, expected %v", got, start)
+	}
+	c.Advance(time.Minute)
+	if got := c.Now(); !got.Equal(start.Add(time.Minute)) {
+		t.Errorf("Now() after Advance got %v, expected %v", got, start.Add(time.Minute))
+	}
+	c.Set(start)
+	if got := c.Now(); !got.Equal(start) {
+		t.Errorf("Now() after Set got %v, expected %v", got, start)
+	}
+}
+
+func TestClockAfter(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewClock(start)
+	done := make(chan time.Time)
+	go func() { done <- <-c.After(time.Second) }()
+	c.BlockUntil(1)
+	c.Advance(999 * time.Millisecond)
+	select {
+	case <-done:
+		t.Fatalf("After(1s) fired after 999ms")
+	default:
+	}
+	c.Advance(time.Millisecond)
+	if got := <-done; !got.Equal(start.Add(time.Second)) {
+		t.Errorf("After(1s) sent %v, expected %v", got, start.Add(time.Second))
+	}
+	select {
+	case <-c.After(0):
+	default:
+		t.Errorf("After(0) did not fire immediately")
+	}
+}
@@ -0,0 +1,154 @@
+package progressio
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"time"
+)
+
+// progressJSON is the wire format of Progress. The pointers are nil for the
+// values which are unknown, so they are left out.
+type progressJSON struct {
+	Transferred      int64      `json:"transferred"`
+	TransferredHuman string     `json:"transferred_human"`
+	Session          int64      `json:"session"`
+	TotalSize        *int64     `json:"total_size,omitempty"`
+	TotalSizeHuman   string     `json:"total_size_human,omitempty"`
+	Percent          *float64   `json:"percent,omitempty"`
+	Speed            *int64     `json:"speed,omitempty"`
+	SpeedHuman       string     `json:"speed_human,omitempty"`
+	SpeedAvg         *int64     `json:"speed_avg,omitempty"`
+	SpeedAvgHuman    string     `json:"speed_avg_human,omitempty"`
+	Remaining        *float64   `json:"remaining,omitempty"`
+	RemainingHuman   string     `json:"remaining_human,omitempty"`
+	Estimator        string     `json:"estimator,omitempty"`
+	Confidence       float64    `json:"confidence,omitempty"`
+	Elapsed          *float64   `json:"elapsed,omitempty"`
+	ElapsedHuman     string     `json:"elapsed_human,omitempty"`
+	StartTime        *time.Time `json:"start_time,omitempty"`
+	StopTime         *time.Time `json:"stop_time,omitempty"`
+	Error            string     `json:"error,omitempty"`
+	Throttled        bool       `json:"throttled,omitempty"`
+	State            State      `json:"state"`
+}
+
+// MarshalJSON encodes the progress as a JSON object with snake_case field
+// names. Sizes are in bytes and speeds in bytes/sec, durations are in seconds
+// and times in RFC 3339 format. The fields which are unknown are left out: the
+// total size and percentage if the size is unknown, the speeds and the time
+// remaining if they could not be calculated yet, and the times, elapsed time
+// and error if they are not set. Sizes, speeds and durations are accompanied by
+// a human readable "_human" field, formatted using FormatSize and
+// FormatDuration:
+//
+//	{
+//	  "transferred": 1048576, "transferred_human": "1.00MiB",
+//	  "session": 1048576,
+//	  "total_size": 4194304, "total_size_human": "4.00MiB",
+//	  "percent": 25,
+//	  "speed": 524288, "speed_human": "512.00KiB/s",
+//	  "speed_avg": 524288, "speed_avg_human": "512.00KiB/s",
+//	  "remaining": 6, "remaining_human": "6 seconds",
+//	  "estimator": "average", "confidence": 0.25,
+//	  "elapsed": 2, "elapsed_human": "2 seconds",
+//	  "start_time": "2020-01-01T00:00:00Z",
+//	  "state": "running"
+//	}
+//
+// The elapsed time is calculated when encoding, and ignored when decoding.
+func (p Progress) MarshalJSON() ([]byte, error) {
+	j := progressJSON{
+		Transferred:      p.Transferred,
+		TransferredHuman: FormatSize(IEC, p.Transferred, true),
+		Session:          p.Session,
+		Estimator:        p.Estimator,
+		Confidence:       p.Confidence,
+		Throttled:        p.Throttled,
+		State:            p.State,
+	}
+	if p.TotalSize > 0 {
+		j.TotalSize = &p.TotalSize
+		j.TotalSizeHuman = FormatSize(IEC, p.TotalSize, true)
+		j.Percent = &p.Percent
+	}
+	if p.Speed >= 0 {
+		j.Speed = &p.Speed
+		j.SpeedHuman = FormatSize(IEC, p.Speed, true) + "/s"
+	}
+	if p.SpeedAvg >= 0 {
+		j.SpeedAvg = &p.SpeedAvg
+		j.SpeedAvgHuman = FormatSize(IEC, p.SpeedAvg, true) + "/s"
+	}
+	if p.Remaining >= 0 {
+		remaining := p.Remaining.Seconds()
+		j.Remaining = &remaining
+		j.RemainingHuman = FormatDuration(p.Remaining)
+	}
+	if !p.StartTime.IsZero() {
+		elapsed := p.Elapsed()
+		seconds := elapsed.Seconds()
+		j.StartTime = &p.StartTime
+		j.Elapsed = &seconds
+		j.ElapsedHuman = FormatDuration(elapsed)
+	}
+	if !p.StopTime.IsZero() {
+		j.StopTime = &p.StopTime
+	}
+	if p.Err != nil {
+		j.Error = p.Err.Error()
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON decodes the progress encoded by MarshalJSON. The fields which
+// are left out are set to their unknown value: -1 for the total size, speeds
+// and time remaining. The error only retains its message.
+func (p *Progress) UnmarshalJSON(data []byte) error {
+	var j progressJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	*p = Progress{
+		Transferred: j.Transferred,
+		Session:     j.Session,
+		TotalSize:   -1,
+		SpeedAvg:    -1,
+		Speed:       -1,
+		Remaining:   -1,
+		Estimator:   j.Estimator,
+		Confidence:  j.Confidence,
+		Throttled:   j.Throttled,
+		State:       j.State,
+	}
+	if j.TotalSize != nil {
+		p.TotalSize = *j.TotalSize
+	}
+	if j.Percent != nil {
+		p.Percent = *j.Percent
+	}
+	if j.Speed != nil {
+		p.Speed = *j.Speed
+	}
+	if j.SpeedAvg != nil {
+		p.SpeedAvg = *j.SpeedAvg
+	}
+	if j.Remaining != nil {
+		p.Remaining = time.Duration(math.Round(*j.Remaining * float64(time.Second)))
+	}
+	if j.StartTime != nil {
+		p.StartTime = *j.StartTime
+	}
+	if j.StopTime != nil {
+		p.StopTime = *j.StopTime
+	}
+	if j.Error != "" {
+		p.Err = errors.New(j.Error)
+	}
+	return nil
+}
+
+// MarshalText encodes the progress as its string representation, see String.
+func (p Progress) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
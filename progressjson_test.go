@@ -0,0 +1,77 @@
+package progressio
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bartmeuris/progressio/progressiotest"
+)
+
+func TestProgressJSON(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := progressiotest.NewClock(start.Add(2 * time.Second))
+	running := Progress{
+		Transferred: 1048576,
+		Session:     1048576,
+		TotalSize:   4194304,
+		Percent:     25,
+		Speed:       524288,
+		SpeedAvg:    524288,
+		Remaining:   6 * time.Second,
+		Estimator:   "average",
+		Confidence:  0.25,
+		StartTime:   start,
+		State:       StateRunning,
+		clock:       clock,
+	}
+	b, err := json.Marshal(running)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"transferred":1048576,"transferred_human":"1.00MiB","session":1048576,` +
+		`"total_size":4194304,"total_size_human":"4.00MiB","percent":25,` +
+		`"speed":524288,"speed_human":"512.00KiB/s","speed_avg":524288,"speed_avg_human":"512.00KiB/s",` +
+		`"remaining":6,"remaining_human":"6 seconds","estimator":"average","confidence":0.25,` +
+		`"elapsed":2,"elapsed_human":"2 seconds","start_time":"2020-01-01T00:00:00Z","state":"running"}`
+	if string(b) != want {
+		t.Errorf("Got\n%s\nwant\n%s", b, want)
+	}
+
+	unknown := Progress{Transferred: 10, Session: 10, TotalSize: -1, Speed: -1, SpeedAvg: -1, Remaining: -1}
+	b, _ = json.Marshal(unknown)
+	if want := `{"transferred":10,"transferred_human":"10B","session":10,"state":"running"}`; string(b) != want {
+		t.Errorf("Got %s, want %s", b, want)
+	}
+
+	failed := running
+	failed.clock = nil
+	failed.StopTime = start.Add(1500 * time.Millisecond)
+	failed.Remaining = 1234567891 * time.Nanosecond
+	failed.Err = errors.New("broken pipe")
+	failed.State = StateFailed
+	for _, p := range []Progress{failed, unknown} {
+		b, err := json.Marshal(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got Progress
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Fatalf("Unmarshal(%s) failed: %v", b, err)
+		}
+		if (got.Err == nil) != (p.Err == nil) || (got.Err != nil && got.Err.Error() != p.Err.Error()) {
+			t.Errorf("Round trip error: %v, want %v", got.Err, p.Err)
+		}
+		got.Err, p.Err = nil, nil
+		if !reflect.DeepEqual(got, p) {
+			t.Errorf("Round trip:\n%+v\nwant\n%+v", got, p)
+		}
+	}
+
+	var s State
+	if err := json.Unmarshal([]byte(`"bogus"`), &s); err == nil {
+		t.Errorf("Unmarshal of an unknown state succeeded")
+	}
+}
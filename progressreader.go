@@ -1,16 +1,17 @@
 package progressio
 
 import (
+	"context"
 	"io"
-	"io/ioutil"
 	"os"
 )
 
 // ProgressReader is a struct representing an io.ReaderCloser, which sends back progress
-// feedback over a channel
+// feedback over a channel. It also implements io.Seeker, io.ReaderAt and io.WriterTo,
+// using the wrapped reader when it supports them.
 type ProgressReader struct {
-	r io.ReadCloser
-	ioProgress
+	r io.Reader
+	*ioProgress
 }
 
 // NewProgressFileReader creates a new ProgressReader based on a file. It teturns a
@@ -35,28 +36,139 @@ func NewProgressFileReader(file string) (*ProgressReader, <-chan Progress, error
 // NewProgressReader creates a new ProgressReader object based on the io.Reader and the
 // size you specified. Specify a size <= 0 if you don't know the size.
 func NewProgressReader(r io.Reader, size int64) (*ProgressReader, <-chan Progress) {
-	if r == nil {
+	ch := make(chan Progress)
+	ret := NewProgressReaderWithOptions(r, size, WithChannel(ch), WithGuaranteedFinal())
+	if ret == nil {
 		return nil, nil
 	}
-	rc, ok := r.(io.ReadCloser)
-	if !ok {
-		rc = ioutil.NopCloser(r)
+	return ret, ch
+}
+
+// NewProgressReaderWithOptions creates a new ProgressReader object based on the io.Reader
+// and the size you specified, configured by the options. Use options like WithCallback
+// and WithChannel to receive the progress. Specify a size <= 0 if you don't know the size.
+func NewProgressReaderWithOptions(r io.Reader, size int64, opts ...Option) *ProgressReader {
+	if r == nil {
+		return nil
 	}
-	ret := &ProgressReader{rc, *mkIoProgress(size)}
-	return ret, ret.ch
+	return &ProgressReader{r, mkIoProgress(size, opts...)}
+}
+
+// NewProgressReaderContext creates a new ProgressReader object like NewProgressReader,
+// but bound to the specified context. Once the context is cancelled, Read returns
+// the context's error and the final Progress carrying that error is sent before the
+// channel is closed.
+func NewProgressReaderContext(ctx context.Context, r io.Reader, size int64) (*ProgressReader, <-chan Progress) {
+	ret, ch := NewProgressReader(r, size)
+	if ret != nil {
+		ret.ctx = ctx
+	}
+	return ret, ch
 }
 
 // Read wraps the io.Reader Read function to also update the progress.
 func (p *ProgressReader) Read(b []byte) (n int, err error) {
+	if err = p.ctxErr(); err != nil {
+		return 0, err
+	}
+	if err = p.waitResume(); err != nil {
+		return 0, err
+	}
+	if max := p.chunkSize(); max > 0 && len(b) > max {
+		b = b[:max]
+	}
 	n, err = p.r.Read(b)
+	// Reserve before updating, so the update reports if this read is throttled
+	wait := p.reserve(int64(n))
 	p.updateProgress(int64(n))
+	if terr := p.sleep(wait); terr != nil && err == nil {
+		err = terr
+	}
+	if err != nil {
+		p.finish(err)
+	}
 	return
 }
 
+// Seek wraps the io.Seeker Seek function, moving the progress to the new offset:
+// seeking back means the data is read again. It fails with errors.ErrUnsupported
+// if the wrapped reader is not an io.Seeker.
+func (p *ProgressReader) Seek(offset int64, whence int) (int64, error) {
+	s, ok := p.r.(io.Seeker)
+	if !ok {
+		return 0, unsupported(p.r, "io.Seeker")
+	}
+	pos, err := s.Seek(offset, whence)
+	if err == nil {
+		p.seekProgress(pos)
+	}
+	return pos, err
+}
+
+// ReadAt wraps the io.ReaderAt ReadAt function. Every byte is only counted once,
+// no matter how often and in which order the ranges are read. Once ReadAt is
+// used, this includes the bytes read by Read, and Seek no longer moves the
+// progress. It fails with errors.ErrUnsupported if the wrapped reader is not an
+// io.ReaderAt.
+func (p *ProgressReader) ReadAt(b []byte, off int64) (int, error) {
+	ra, ok := p.r.(io.ReaderAt)
+	if !ok {
+		return 0, unsupported(p.r, "io.ReaderAt")
+	}
+	return p.readAt(ra, b, off)
+}
+
+// WriteTo implements io.WriterTo, so io.Copy keeps using the fast paths of the
+// wrapped reader and of w, like sendfile and splice, while the progress is
+// updated.
+func (p *ProgressReader) WriteTo(w io.Writer) (n int64, err error) {
+	if rf, ok := w.(io.ReaderFrom); ok {
+		n, err = p.copyChunks(rf, p.r)
+	} else if wt, ok := p.r.(io.WriterTo); ok {
+		n, err = wt.WriteTo(&ProgressWriter{w, p.ioProgress})
+	} else {
+		// Read does the bookkeeping, hide WriteTo to prevent recursion
+		n, err = io.Copy(w, struct{ io.Reader }{p})
+	}
+	p.finish(err)
+	return
+}
+
+// Reader returns p as an io.ReadCloser which only implements io.Seeker and
+// io.ReaderAt if the wrapped reader does, for code detecting them using type
+// assertions.
+func (p *ProgressReader) Reader() io.ReadCloser {
+	_, seeker := p.r.(io.Seeker)
+	_, readerAt := p.r.(io.ReaderAt)
+	switch {
+	case seeker && readerAt:
+		return p
+	case seeker:
+		return struct {
+			readCloserWriterTo
+			io.Seeker
+		}{p, p}
+	case readerAt:
+		return struct {
+			readCloserWriterTo
+			io.ReaderAt
+		}{p, p}
+	}
+	return struct{ readCloserWriterTo }{p}
+}
+
+type readCloserWriterTo interface {
+	io.ReadCloser
+	io.WriterTo
+}
+
 // Close wraps the io.ReaderCloser Close function to clean up everything. ProgressReader
-// objects should always be closed to make sure everything is cleaned up.
+// objects should always be closed to make sure everything is cleaned up. If the size
+// is known and was not read completely, the transfer fails with ErrIncomplete.
 func (p *ProgressReader) Close() (err error) {
-	err = p.r.Close()
-	p.stopProgress()
+	if c, ok := p.r.(io.Closer); ok {
+		err = c.Close()
+	}
+	p.stopProgress(err)
 	return
 }
@@ -1,47 +1,146 @@
 package progressio
 
-import "io"
-
-// Copy functionality of ioutil.NopCloser, but for Writers
-type nopWriteCloser struct{ io.Writer }
-
-func (nopWriteCloser) Close() error { return nil }
-func getNopWriteCloser(w io.Writer) io.WriteCloser {
-	return nopWriteCloser{w}
-}
+import (
+	"context"
+	"io"
+)
 
 // ProgressWriter is a struct representing an io.WriterCloser, which sends back progress
-// feedback over a channel
+// feedback over a channel. It also implements io.Seeker and io.ReaderFrom, using the
+// wrapped writer when it supports them.
 type ProgressWriter struct {
-	w io.WriteCloser
-	ioProgress
+	w io.Writer
+	*ioProgress
 }
 
 // NewProgressWriter creates a new ProgressWriter object based on the io.Writer and the
 // size you specified. Specify a size <= 0 if you don't know the size.
 func NewProgressWriter(w io.Writer, size int64) (*ProgressWriter, <-chan Progress) {
-	if w == nil {
+	ch := make(chan Progress)
+	ret := NewProgressWriterWithOptions(w, size, WithChannel(ch), WithGuaranteedFinal())
+	if ret == nil {
 		return nil, nil
 	}
-	wc, ok := w.(io.WriteCloser)
-	if !ok {
-		wc = getNopWriteCloser(w)
+	return ret, ch
+}
+
+// NewProgressWriterWithOptions creates a new ProgressWriter object based on the io.Writer
+// and the size you specified, configured by the options. Use options like WithCallback
+// and WithChannel to receive the progress. Specify a size <= 0 if you don't know the size.
+func NewProgressWriterWithOptions(w io.Writer, size int64, opts ...Option) *ProgressWriter {
+	if w == nil {
+		return nil
 	}
-	ret := &ProgressWriter{wc, *mkIoProgress(size)}
-	return ret, ret.ch
+	return &ProgressWriter{w, mkIoProgress(size, opts...)}
+}
+
+// NewProgressWriterContext creates a new ProgressWriter object like NewProgressWriter,
+// but bound to the specified context. Once the context is cancelled, Write returns
+// the context's error and the final Progress carrying that error is sent before the
+// channel is closed.
+func NewProgressWriterContext(ctx context.Context, w io.Writer, size int64) (*ProgressWriter, <-chan Progress) {
+	ret, ch := NewProgressWriter(w, size)
+	if ret != nil {
+		ret.ctx = ctx
+	}
+	return ret, ch
 }
 
 // Write wraps the io.Writer Write function to also update the progress.
 func (p *ProgressWriter) Write(b []byte) (n int, err error) {
-	n, err = p.w.Write(b[0:])
-	p.updateProgress(int64(n))
+	defer func() {
+		if err != nil {
+			p.stopProgress(err)
+		}
+	}()
+	if err = p.ctxErr(); err != nil {
+		return 0, err
+	}
+	if err = p.waitResume(); err != nil {
+		return 0, err
+	}
+	max := p.chunkSize()
+	if max <= 0 {
+		n, err = p.w.Write(b[0:])
+		p.updateProgress(int64(n))
+		return
+	}
+	// Write in chunks of at most the burst size of the rate limiter, waiting
+	// for the limiter before every chunk
+	for len(b) > 0 {
+		chunk := b
+		if len(chunk) > max {
+			chunk = chunk[:max]
+		}
+		if err = p.throttle(int64(len(chunk))); err != nil {
+			return
+		}
+		var m int
+		m, err = p.w.Write(chunk)
+		n += m
+		p.updateProgress(int64(m))
+		if err != nil {
+			return
+		}
+		if m < len(chunk) {
+			return n, io.ErrShortWrite
+		}
+		b = b[m:]
+	}
 	return
 }
 
+// Seek wraps the io.Seeker Seek function, moving the progress to the new offset:
+// seeking back means the data is written again. It fails with
+// errors.ErrUnsupported if the wrapped writer is not an io.Seeker.
+func (p *ProgressWriter) Seek(offset int64, whence int) (int64, error) {
+	s, ok := p.w.(io.Seeker)
+	if !ok {
+		return 0, unsupported(p.w, "io.Seeker")
+	}
+	pos, err := s.Seek(offset, whence)
+	if err == nil {
+		p.seekProgress(pos)
+	}
+	return pos, err
+}
+
+// ReadFrom implements io.ReaderFrom, so io.Copy keeps using the fast paths of the
+// wrapped writer, like sendfile and splice, while the progress is updated.
+func (p *ProgressWriter) ReadFrom(r io.Reader) (n int64, err error) {
+	if rf, ok := p.w.(io.ReaderFrom); ok {
+		n, err = p.copyChunks(rf, r)
+	} else {
+		// Write does the bookkeeping, hide ReadFrom to prevent recursion
+		n, err = io.Copy(struct{ io.Writer }{p}, r)
+	}
+	if err != nil {
+		p.stopProgress(err)
+	}
+	return
+}
+
+// Writer returns p as an io.WriteCloser which only implements io.Seeker if the
+// wrapped writer does, for code detecting it using a type assertion.
+func (p *ProgressWriter) Writer() io.WriteCloser {
+	if _, ok := p.w.(io.Seeker); ok {
+		return p
+	}
+	return struct{ writeCloserReaderFrom }{p}
+}
+
+type writeCloserReaderFrom interface {
+	io.WriteCloser
+	io.ReaderFrom
+}
+
 // Close wraps the io.WriterCloser Close function to clean up everything. ProgressWriter
-// objects should always be closed to make sure everything is cleaned up.
+// objects should always be closed to make sure everything is cleaned up. If the size
+// is known and was not written completely, the transfer fails with ErrIncomplete.
 func (p *ProgressWriter) Close() (err error) {
-	err = p.w.Close()
-	p.stopProgress()
+	if c, ok := p.w.(io.Closer); ok {
+		err = c.Close()
+	}
+	p.stopProgress(err)
 	return
 }
@@ -0,0 +1,80 @@
+package progressio
+
+import "sort"
+
+// Range is the range of bytes from Start up to, but not including, End.
+type Range struct {
+	Start int64
+	End   int64
+}
+
+// Len returns the amount of bytes in the range.
+func (r Range) Len() int64 {
+	return r.End - r.Start
+}
+
+// rangeSet is a set of byte ranges, used to count the unique bytes transferred
+// when they are accessed in random order. The ranges are kept sorted, and
+// overlapping or adjacent ranges are merged.
+type rangeSet struct {
+	ranges []Range
+}
+
+// add adds the range [start, end) to the set, and returns the amount of bytes
+// which were not in the set yet.
+func (s *rangeSet) add(start, end int64) int64 {
+	if end <= start {
+		return 0
+	}
+	// The first range which overlaps or touches the new one
+	i := sort.Search(len(s.ranges), func(i int) bool { return s.ranges[i].End >= start })
+	added := end - start
+	merged := Range{start, end}
+	j := i
+	for ; j < len(s.ranges) && s.ranges[j].Start <= end; j++ {
+		r := s.ranges[j]
+		if overlap := min64(r.End, end) - max64(r.Start, start); overlap > 0 {
+			added -= overlap
+		}
+		merged.Start = min64(merged.Start, r.Start)
+		merged.End = max64(merged.End, r.End)
+	}
+	s.ranges = append(s.ranges[:i], append([]Range{merged}, s.ranges[j:]...)...)
+	return added
+}
+
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// list returns a copy of the ranges in the set.
+func (s *rangeSet) list() []Range {
+	return append([]Range(nil), s.ranges...)
+}
+
+// holes returns the ranges between 0 and size which are missing from the set.
+// If size <= 0, only the holes in between the ranges of the set are returned.
+func (s *rangeSet) holes(size int64) []Range {
+	var ret []Range
+	pos := int64(0)
+	for _, r := range s.ranges {
+		if r.Start > pos {
+			ret = append(ret, Range{pos, r.Start})
+		}
+		pos = r.End
+	}
+	if size > pos {
+		ret = append(ret, Range{pos, size})
+	}
+	return ret
+}
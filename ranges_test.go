@@ -0,0 +1,53 @@
+package progressio
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRangeSet(t *testing.T) {
+	var s rangeSet
+	steps := []struct {
+		start, end int64
+		added      int64
+		want       []Range
+	}{
+		{10, 20, 10, []Range{{10, 20}}},
+		{30, 40, 10, []Range{{10, 20}, {30, 40}}},
+		{15, 25, 5, []Range{{10, 25}, {30, 40}}},
+		{0, 5, 5, []Range{{0, 5}, {10, 25}, {30, 40}}},
+		{5, 10, 5, []Range{{0, 25}, {30, 40}}},
+		{12, 18, 0, []Range{{0, 25}, {30, 40}}},
+		{20, 50, 15, []Range{{0, 50}}},
+		{60, 60, 0, []Range{{0, 50}}},
+	}
+	for _, st := range steps {
+		if added := s.add(st.start, st.end); added != st.added {
+			t.Errorf("add(%d, %d) = %d, want %d", st.start, st.end, added, st.added)
+		}
+		if !reflect.DeepEqual(s.ranges, st.want) {
+			t.Errorf("After add(%d, %d): ranges %v, want %v", st.start, st.end, s.ranges, st.want)
+		}
+	}
+}
+
+func TestRangeSetHoles(t *testing.T) {
+	s := rangeSet{ranges: []Range{{10, 20}, {30, 40}}}
+	tests := []struct {
+		size int64
+		want []Range
+	}{
+		{50, []Range{{0, 10}, {20, 30}, {40, 50}}},
+		{40, []Range{{0, 10}, {20, 30}}},
+		{-1, []Range{{0, 10}, {20, 30}}},
+	}
+	for _, tt := range tests {
+		if got := s.holes(tt.size); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("holes(%d) = %v, want %v", tt.size, got, tt.want)
+		}
+	}
+	var empty rangeSet
+	if got := empty.holes(5); !reflect.DeepEqual(got, []Range{{0, 5}}) {
+		t.Errorf("Empty holes(5) = %v", got)
+	}
+}
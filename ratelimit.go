@@ -0,0 +1,88 @@
+package progressio
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter limits the throughput of the wrappers using it with a token
+// bucket: the bucket is filled with the allowed amount of bytes per second,
+// up to the burst size, and every transferred byte takes a token. A
+// RateLimiter is safe for concurrent use, so sharing one between several
+// wrappers keeps them under one global limit. The bucket is refilled according
+// to the Clock of the wrappers, which have to share the same Clock.
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   int64 // bytes/sec, <= 0 if unlimited
+	burst  int64
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter creates a new RateLimiter allowing bytesPerSec bytes per second,
+// with bursts of up to burst bytes. If burst <= 0, it is set to bytesPerSec.
+// If bytesPerSec <= 0, the throughput is not limited.
+func NewRateLimiter(bytesPerSec, burst int64) *RateLimiter {
+	l := &RateLimiter{}
+	l.SetRate(bytesPerSec, burst)
+	return l
+}
+
+// SetRate changes the limits of the RateLimiter, see NewRateLimiter. It can be
+// called while transfers are running.
+func (l *RateLimiter) SetRate(bytesPerSec, burst int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if burst <= 0 {
+		burst = bytesPerSec
+	}
+	l.rate = bytesPerSec
+	l.burst = burst
+	if l.tokens > float64(burst) {
+		l.tokens = float64(burst)
+	}
+}
+
+// Rate returns the current limits of the RateLimiter.
+func (l *RateLimiter) Rate() (bytesPerSec, burst int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate, l.burst
+}
+
+// reserve takes n tokens out of the bucket at the time now, according to the
+// Clock of the wrapper, and returns how long to wait until they are available.
+// The bucket can go into debt, so large transfers are not blocked forever, but
+// have to wait proportionally.
+func (l *RateLimiter) reserve(n int64, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.rate <= 0 {
+		return 0
+	}
+	if l.last.IsZero() {
+		l.tokens = float64(l.burst)
+	} else {
+		l.tokens += now.Sub(l.last).Seconds() * float64(l.rate)
+		if l.tokens > float64(l.burst) {
+			l.tokens = float64(l.burst)
+		}
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / float64(l.rate) * float64(time.Second))
+}
+
+// chunkSize returns the maximum amount of bytes to transfer at once, to keep the
+// throughput smooth. It returns 0 if the throughput is not limited.
+func (l *RateLimiter) chunkSize() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.rate <= 0 {
+		return 0
+	}
+	return int(l.burst)
+}
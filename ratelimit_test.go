@@ -0,0 +1,176 @@
+package progressio
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bartmeuris/progressio/progressiotest"
+)
+
+// waitFor fails the test if ch is not closed soon, instead of hanging when a
+// transfer waits for the clock to be advanced again.
+func waitFor(t *testing.T, ch <-chan struct{}, what string) {
+	t.Helper()
+	select {
+	case <-ch:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%s did not return", what)
+	}
+}
+
+func TestRateLimiterReserve(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewRateLimiter(1000, 100)
+	if wait := l.reserve(100, now); wait != 0 {
+		t.Errorf("reserve() within burst waits %v, expected 0", wait)
+	}
+	// The bucket is empty: 500 bytes take half a second at 1000 bytes/sec
+	if wait := l.reserve(500, now); wait != 500*time.Millisecond {
+		t.Errorf("reserve() in debt waits %v, expected 500ms", wait)
+	}
+	// The debt is paid off after 500ms, the bucket refills after 100ms more
+	if wait := l.reserve(100, now.Add(time.Second)); wait != 0 {
+		t.Errorf("reserve() after refilling waits %v, expected 0", wait)
+	}
+	l.SetRate(0, 0)
+	if wait := l.reserve(1000000, now); wait != 0 {
+		t.Errorf("reserve() unlimited waits %v, expected 0", wait)
+	}
+	if rate, burst := l.Rate(); rate != 0 || burst != 0 {
+		t.Errorf("Rate() = %d, %d, expected 0, 0", rate, burst)
+	}
+}
+
+func TestRateLimitWriter(t *testing.T) {
+	clock := progressiotest.NewClock(time.Now())
+	start := clock.Now()
+	var last Progress
+	var throttled bool
+	w := NewProgressWriterWithOptions(ioutil.Discard, 3000,
+		WithClock(clock),
+		WithRateLimit(10000, 1000),
+		WithUpdateInterval(0),
+		WithCallback(func(p Progress) {
+			last = p
+			throttled = throttled || p.Throttled
+		}),
+	)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if n, err := w.Write(make([]byte, 3000)); n != 3000 || err != nil {
+			t.Errorf("Write() = %d, %v", n, err)
+		}
+	}()
+	// The first 1000 bytes are the burst, the next chunks of 1000 bytes wait
+	// 100ms each for the bucket to refill
+	for i := 0; i < 2; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(100 * time.Millisecond)
+	}
+	waitFor(t, done, "Write()")
+	if took := clock.Now().Sub(start); took != 200*time.Millisecond {
+		t.Errorf("Write() took %v, expected 200ms", took)
+	}
+	if !throttled {
+		t.Errorf("Progress never reported being throttled")
+	}
+	if last.Transferred != 3000 {
+		t.Errorf("Got transferred %d, expected 3000", last.Transferred)
+	}
+
+	// Without limit, the write never waits for the clock
+	w.SetRateLimit(0, 0)
+	done = make(chan struct{})
+	go func() {
+		defer close(done)
+		w.Write(make([]byte, 100000))
+	}()
+	waitFor(t, done, "Write() without limit")
+}
+
+func TestRateLimitReaderThrottled(t *testing.T) {
+	clock := progressiotest.NewClock(time.Now())
+	var throttled []bool
+	r := NewProgressReaderWithOptions(zeroReader{}, -1,
+		WithClock(clock),
+		WithRateLimit(10000, 100),
+		WithUpdateInterval(0),
+		WithCallback(func(p Progress) { throttled = append(throttled, p.Throttled) }),
+	)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		b := make([]byte, 100)
+		r.Read(b) // within the burst
+		r.Read(b) // has to wait 10ms for the bucket to refill
+	}()
+	clock.BlockUntil(1)
+	clock.Advance(10 * time.Millisecond)
+	waitFor(t, done, "Read()")
+	r.Close()
+	// The update of a read reports if that read was throttled
+	if len(throttled) < 2 || throttled[0] || !throttled[1] {
+		t.Errorf("Got throttled %v, expected [false true ...]", throttled)
+	}
+}
+
+func TestSharedRateLimiter(t *testing.T) {
+	clock := progressiotest.NewClock(time.Now())
+	start := clock.Now()
+	l := NewRateLimiter(20000, 1000)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		r := NewProgressReaderWithOptions(io.LimitReader(zeroReader{}, 1500), 1500,
+			WithClock(clock), WithRateLimiter(l))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			io.Copy(ioutil.Discard, r)
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	// Move the clock along until all readers are done
+	timeout := time.After(5 * time.Second)
+	for finished := false; !finished; {
+		select {
+		case <-done:
+			finished = true
+		case <-timeout:
+			t.Fatalf("Readers did not finish")
+		default:
+			clock.Advance(time.Millisecond)
+			runtime.Gosched()
+		}
+	}
+	// 6000 bytes, of which 1000 burst, at 20000 bytes/sec: 250ms
+	if took := clock.Now().Sub(start); took < 250*time.Millisecond {
+		t.Errorf("Shared limit took %v, expected at least 250ms", took)
+	}
+}
+
+func TestRateLimitContext(t *testing.T) {
+	clock := progressiotest.NewClock(time.Now())
+	ctx, cancel := context.WithCancel(context.Background())
+	w := NewProgressWriterWithOptions(ioutil.Discard, -1, WithClock(clock), WithContext(ctx), WithRateLimit(10, 10))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := w.Write(make([]byte, 100)); err != context.Canceled {
+			t.Errorf("Write() error = %v, expected %v", err, context.Canceled)
+		}
+	}()
+	// Cancel while the write waits for the bucket to refill
+	clock.BlockUntil(1)
+	cancel()
+	waitFor(t, done, "Write()")
+}
@@ -0,0 +1,206 @@
+package term
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/bartmeuris/progressio"
+)
+
+// Multi draws the progress of several concurrent transfers as a stack of bars,
+// followed by a line with the total progress. On a terminal, the stack is
+// redrawn periodically by moving the cursor up, and the bar of a completed
+// transfer is collapsed into its summary, which is printed above the stack.
+// When the output is not a terminal, log lines are written like Bar does.
+//
+// All output is written by a single goroutine at a time, so transfers running
+// in parallel goroutines do not interleave their output.
+type Multi struct {
+	Width       int           // Width of the lines, <= 0 to detect it
+	TTY         bool          // If the output is a terminal, detected by NewMulti
+	Interval    time.Duration // Time between two redraws on a terminal
+	LogInterval time.Duration // Time between log lines if the output is not a terminal
+
+	out   io.Writer
+	mu    sync.Mutex
+	bars  []*multiBar
+	lines int // the amount of lines of the stack drawn last
+	wg    sync.WaitGroup
+	once  sync.Once
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+type multiBar struct {
+	bar       *Bar
+	last      progressio.Progress
+	finished  bool // the channel was closed
+	collapsed bool // the summary was printed
+}
+
+// NewMulti creates a new Multi writing to out, detecting if out is a terminal.
+func NewMulti(out io.Writer) *Multi {
+	return &Multi{
+		TTY:         IsTerminal(out),
+		Interval:    progressio.UpdateFreq,
+		LogInterval: DefaultLogInterval,
+		out:         out,
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+// Add adds a transfer to the display, drawing the updates received over the
+// channel until it is closed.
+func (m *Multi) Add(label string, ch <-chan progressio.Progress) {
+	mb := &multiBar{bar: &Bar{
+		Label:       label,
+		TTY:         m.TTY,
+		LogInterval: m.LogInterval,
+		out:         m.out,
+	}}
+	m.mu.Lock()
+	m.bars = append(m.bars, mb)
+	m.mu.Unlock()
+
+	m.once.Do(func() { go m.run() })
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		for p := range ch {
+			m.mu.Lock()
+			mb.last = p
+			if !m.TTY {
+				mb.bar.Draw(p)
+			}
+			m.mu.Unlock()
+		}
+		m.mu.Lock()
+		mb.finished = true
+		if !m.TTY {
+			mb.bar.Finish(mb.last)
+		}
+		m.mu.Unlock()
+	}()
+}
+
+// Wait waits until the channels of all transfers added are closed, and draws
+// the final state. It has to be called before writing anything else to the
+// output, and Add can not be called anymore afterwards.
+func (m *Multi) Wait() {
+	m.wg.Wait()
+	m.once.Do(func() { close(m.done) })
+	close(m.stop)
+	<-m.done
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.TTY {
+		m.redraw()
+	} else {
+		fmt.Fprintf(m.out, "total: %s\n", Summary(m.total()))
+	}
+}
+
+// run redraws the stack until Wait is called
+func (m *Multi) run() {
+	defer close(m.done)
+	if !m.TTY {
+		<-m.stop
+		return
+	}
+	t := time.NewTicker(m.Interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			m.mu.Lock()
+			m.redraw()
+			m.mu.Unlock()
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+// redraw draws the stack over the previous one, m.mu must be held. The
+// output is written at once, to prevent flickering.
+func (m *Multi) redraw() {
+	var buf bytes.Buffer
+	if m.lines > 0 {
+		fmt.Fprintf(&buf, "\x1b[%dA", m.lines)
+	}
+	width := m.Width
+	if width <= 0 {
+		width = TerminalWidth(m.out)
+	}
+	// Collapse the completed transfers: print their summary above the stack
+	for _, mb := range m.bars {
+		if mb.finished && !mb.collapsed {
+			fmt.Fprintf(&buf, "\r%s\x1b[K\n", truncate(mb.bar.prefix()+Summary(mb.last), width))
+			mb.collapsed = true
+		}
+	}
+	m.lines = 0
+	for _, mb := range m.bars {
+		if !mb.collapsed {
+			fmt.Fprintf(&buf, "\r%s\x1b[K\n", mb.bar.Line(mb.last, width))
+			m.lines++
+		}
+	}
+	// The total line becomes a summary once all transfers are completed
+	total := &Bar{Label: "total"}
+	line := total.Line(m.total(), width)
+	if m.lines == 0 {
+		line = truncate(total.prefix()+Summary(m.total()), width)
+	}
+	fmt.Fprintf(&buf, "\r%s\x1b[K\n\x1b[J", line)
+	m.lines++
+	m.out.Write(buf.Bytes())
+}
+
+// total returns the combined progress of all transfers, m.mu must be held.
+// The size is unknown if the size of any transfer is unknown.
+func (m *Multi) total() progressio.Progress {
+	ret := progressio.Progress{Remaining: -1}
+	unknown, active := false, false
+	for _, mb := range m.bars {
+		active = active || !mb.finished
+		p := mb.last
+		ret.Transferred += p.Transferred
+		if p.TotalSize > 0 {
+			ret.TotalSize += p.TotalSize
+		} else {
+			unknown = true
+		}
+		if !mb.finished && p.Speed > 0 {
+			ret.Speed += p.Speed
+		}
+		if p.Err != nil && ret.Err == nil {
+			ret.Err = p.Err
+		}
+		if ret.StartTime.IsZero() || (!p.StartTime.IsZero() && p.StartTime.Before(ret.StartTime)) {
+			ret.StartTime = p.StartTime
+		}
+		if p.StopTime.After(ret.StopTime) {
+			ret.StopTime = p.StopTime
+		}
+	}
+	if unknown {
+		ret.TotalSize = -1
+	} else if ret.TotalSize > 0 {
+		ret.Percent = float64(int64(float64(ret.Transferred)/float64(ret.TotalSize)*10000)) / 100
+		if ret.Speed > 0 {
+			ret.Remaining = time.Duration(float64(ret.TotalSize-ret.Transferred) / float64(ret.Speed) * float64(time.Second))
+		}
+	}
+	if active {
+		ret.StopTime = time.Time{}
+	} else if tp := ret.StopTime.Sub(ret.StartTime); tp > 0 {
+		ret.SpeedAvg = int64(float64(ret.Transferred) / tp.Seconds())
+	}
+	return ret
+}
@@ -0,0 +1,82 @@
+package term
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bartmeuris/progressio"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent use
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestMultiTTY(t *testing.T) {
+	var out syncBuffer
+	m := NewMulti(&out)
+	m.TTY = true
+	m.Width = 60
+	m.Interval = time.Millisecond
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	var wg sync.WaitGroup
+	for _, label := range []string{"a", "b", "c"} {
+		ch := make(chan progressio.Progress)
+		m.Add(label, ch)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := int64(1); i <= 10; i++ {
+				ch <- progressio.Progress{Transferred: i * 10, TotalSize: 100, Percent: float64(i * 10), Remaining: -1, StartTime: start}
+				time.Sleep(time.Millisecond)
+			}
+			ch <- progressio.Progress{Transferred: 100, TotalSize: 100, Percent: 100, StartTime: start, StopTime: start.Add(time.Second)}
+			close(ch)
+		}()
+	}
+	wg.Wait()
+	m.Wait()
+
+	got := out.String()
+	for _, label := range []string{"a", "b", "c"} {
+		if n := strings.Count(got, label+": done: 100B in 1 second"); n != 1 {
+			t.Errorf("Summary of %s printed %d times, want 1", label, n)
+		}
+	}
+	// After the last redraw, only the summary of the total is left of the stack
+	if want := "\rtotal: done: 300B in 1 second (300B/s)\x1b[K\n\x1b[J"; m.lines != 1 || !strings.HasSuffix(got, want) {
+		t.Errorf("Final total line missing, got %q", got)
+	}
+}
+
+func TestMultiNoTTY(t *testing.T) {
+	var out syncBuffer
+	m := NewMulti(&out)
+	ch := make(chan progressio.Progress, 2)
+	ch <- progressio.Progress{Transferred: 10, TotalSize: -1}
+	ch <- progressio.Progress{Transferred: 20, TotalSize: -1}
+	close(ch)
+	m.Add("x", ch)
+	m.Wait()
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[1], "x: done: 20B") || !strings.HasPrefix(lines[2], "total: done: 20B") {
+		t.Errorf("Got output %q", out.String())
+	}
+}
@@ -0,0 +1,206 @@
+/*
+Package term renders the progressio.Progress updates received over a channel
+on a terminal.
+
+On a terminal, a progress bar is redrawn on a single line, showing the
+percentage, speed and time remaining, or a spinner if the size is unknown.
+When the output is not a terminal (e.g. redirected to a file), a plain log
+line is written periodically instead. When the channel is closed, the line is
+replaced by a summary of the transfer:
+
+	pr, ch := progressio.NewProgressReader(myreader, size)
+	defer pr.Close()
+	go term.NewBar(os.Stderr, "download").Run(ch)
+	io.Copy(mywriter, pr)
+*/
+package term
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bartmeuris/progressio"
+)
+
+// DefaultWidth is the width used when the width of the terminal is not known
+const DefaultWidth = 80
+
+// DefaultLogInterval is the time between two log lines when the output is not
+// a terminal
+const DefaultLogInterval = 5 * time.Second
+
+// The minimum width of the bar itself, it is left out if there is less room
+const minBarWidth = 10
+
+var spinner = []string{"|", "/", "-", "\\"}
+
+// Bar draws the progress of a single transfer.
+type Bar struct {
+	Label       string        // Shown in front of the bar
+	Width       int           // Width of the line, <= 0 to detect it
+	TTY         bool          // If the output is a terminal, detected by NewBar
+	LogInterval time.Duration // Time between log lines if the output is not a terminal
+
+	out     io.Writer
+	frame   int
+	lastLog time.Time
+	drawn   bool
+}
+
+// NewBar creates a new Bar writing to out, detecting if out is a terminal.
+func NewBar(out io.Writer, label string) *Bar {
+	return &Bar{
+		Label:       label,
+		TTY:         IsTerminal(out),
+		LogInterval: DefaultLogInterval,
+		out:         out,
+	}
+}
+
+// Render is a shorthand to create a Bar and Run it.
+func Render(out io.Writer, label string, ch <-chan progressio.Progress) progressio.Progress {
+	return NewBar(out, label).Run(ch)
+}
+
+// IsTerminal returns if w is a terminal.
+func IsTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// TerminalWidth returns the width of the terminal w writes to. If it can not be
+// queried from the terminal, the COLUMNS environment variable is used, or
+// DefaultWidth.
+func TerminalWidth(w io.Writer) int {
+	if w := ttyWidth(w); w > 0 {
+		return w
+	}
+	if w, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && w > 0 {
+		return w
+	}
+	return DefaultWidth
+}
+
+// Run draws the updates received over the channel until it is closed, and
+// then replaces the bar by a summary. It returns the last update received.
+func (b *Bar) Run(ch <-chan progressio.Progress) progressio.Progress {
+	var last progressio.Progress
+	for p := range ch {
+		last = p
+		b.Draw(p)
+	}
+	b.Finish(last)
+	return last
+}
+
+// Draw draws a single update. On a terminal the bar is redrawn, otherwise a log
+// line is written if the LogInterval passed since the previous one.
+func (b *Bar) Draw(p progressio.Progress) {
+	if !b.TTY {
+		now := time.Now()
+		if now.Sub(b.lastLog) < b.LogInterval {
+			return
+		}
+		b.lastLog = now
+		fmt.Fprintf(b.out, "%s%s\n", b.prefix(), p.String())
+		return
+	}
+	fmt.Fprintf(b.out, "\r%s\x1b[K", b.Line(p, b.width()))
+	b.drawn = true
+}
+
+// Finish replaces the bar by a summary of the transfer.
+func (b *Bar) Finish(p progressio.Progress) {
+	if b.drawn {
+		fmt.Fprint(b.out, "\r\x1b[K")
+		b.drawn = false
+	}
+	fmt.Fprintf(b.out, "%s%s\n", b.prefix(), Summary(p))
+}
+
+// Line returns the line representing the update, at most width characters long.
+// Every call advances the spinner shown if the size is unknown.
+func (b *Bar) Line(p progressio.Progress, width int) string {
+	right := stats(p)
+	left := b.prefix()
+	if p.TotalSize <= 0 {
+		left += spinner[b.frame%len(spinner)] + " "
+		b.frame++
+		return truncate(left+right, width)
+	}
+	if bw := width - utf8.RuneCountInString(left+right) - 2; bw >= minBarWidth {
+		left += progressio.FormatBar(p.Percent, bw) + " "
+	}
+	return truncate(left+right, width)
+}
+
+func (b *Bar) width() int {
+	if b.Width > 0 {
+		return b.Width
+	}
+	return TerminalWidth(b.out)
+}
+
+func (b *Bar) prefix() string {
+	if b.Label == "" {
+		return ""
+	}
+	return b.Label + ": "
+}
+
+// stats returns the percentage, transferred size, speed and time remaining of
+// the update, as far as they are known.
+func stats(p progressio.Progress) string {
+	var parts []string
+	if p.TotalSize > 0 {
+		parts = append(parts, fmt.Sprintf("%6.2f%%", p.Percent))
+		parts = append(parts, progressio.FormatSize(progressio.IEC, p.Transferred, true)+"/"+
+			progressio.FormatSize(progressio.IEC, p.TotalSize, true))
+	} else {
+		parts = append(parts, progressio.FormatSize(progressio.IEC, p.Transferred, true))
+	}
+	if p.Speed > 0 {
+		parts = append(parts, progressio.FormatSize(progressio.IEC, p.Speed, true)+"/s")
+	}
+	if p.TotalSize > 0 && p.Remaining >= 0 {
+		parts = append(parts, "ETA "+progressio.FormatDuration(p.Remaining))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Summary returns a summary of the completed transfer.
+func Summary(p progressio.Progress) string {
+	if p.Err != nil {
+		return fmt.Sprintf("failed after %s: %v",
+			progressio.FormatSize(progressio.IEC, p.Transferred, true), p.Err)
+	}
+	s := "done: " + progressio.FormatSize(progressio.IEC, p.Transferred, true)
+	if !p.StartTime.IsZero() {
+		s += " in " + progressio.FormatDuration(p.Elapsed())
+	}
+	if p.SpeedAvg > 0 {
+		s += fmt.Sprintf(" (%s/s)", progressio.FormatSize(progressio.IEC, p.SpeedAvg, true))
+	}
+	return s
+}
+
+// truncate limits s to width characters, leaving room for the cursor at the end
+// of the line to prevent the terminal from wrapping.
+func truncate(s string, width int) string {
+	if width > 1 && utf8.RuneCountInString(s) > width-1 {
+		return string([]rune(s)[:width-1])
+	}
+	return s
+}
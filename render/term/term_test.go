@@ -0,0 +1,101 @@
+package term
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bartmeuris/progressio"
+)
+
+func TestLine(t *testing.T) {
+	b := &Bar{Label: "file"}
+	p := progressio.Progress{
+		Transferred: 512 * progressio.KibiByte,
+		TotalSize:   progressio.MebiByte,
+		Percent:     50,
+		Speed:       100 * progressio.KibiByte,
+		Remaining:   5 * time.Second,
+	}
+	want := "file: [=========>         ]  50.00% 512.00KiB/1.00MiB 100.00KiB/s ETA 5 seconds"
+	if got := b.Line(p, 80); got != want {
+		t.Errorf("Line() got %q, want %q", got, want)
+	}
+	// Not enough room for the bar
+	want = "file:  50.00% 512.00KiB/1.00MiB 100.00KiB/s ETA 5 seconds"
+	if got := b.Line(p, 60); got != want {
+		t.Errorf("Line() narrow got %q, want %q", got, want)
+	}
+	if got := b.Line(p, 20); got != want[:19] {
+		t.Errorf("Line() truncated got %q, want %q", got, want[:19])
+	}
+
+	// Unknown size: spinner
+	p = progressio.Progress{Transferred: 2 * progressio.MebiByte, TotalSize: -1, Speed: -1}
+	for _, frame := range []string{"|", "/", "-", "\\", "|"} {
+		if got := b.Line(p, 80); got != "file: "+frame+" 2.00MiB" {
+			t.Errorf("Line() unknown size got %q, want %q", got, "file: "+frame+" 2.00MiB")
+		}
+	}
+}
+
+func TestRunNoTTY(t *testing.T) {
+	var out bytes.Buffer
+	b := NewBar(&out, "copy")
+	if b.TTY {
+		t.Fatalf("NewBar() detected a bytes.Buffer as terminal")
+	}
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	ch := make(chan progressio.Progress, 3)
+	ch <- progressio.Progress{Transferred: 10, TotalSize: 100, Percent: 10, StartTime: start, StopTime: start}
+	ch <- progressio.Progress{Transferred: 20, TotalSize: 100, Percent: 20, StartTime: start, StopTime: start}
+	ch <- progressio.Progress{Transferred: 100, TotalSize: 100, Percent: 100, SpeedAvg: 50, StartTime: start, StopTime: start.Add(2 * time.Second)}
+	close(ch)
+	last := b.Run(ch)
+	if last.Transferred != 100 {
+		t.Errorf("Run() returned %d transferred, want 100", last.Transferred)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	// Only the first update is logged within the log interval, then the summary
+	if len(lines) != 2 {
+		t.Fatalf("Run() wrote %d lines, want 2: %q", len(lines), out.String())
+	}
+	if want := "copy: done: 100B in 2 seconds (50B/s)"; lines[1] != want {
+		t.Errorf("Run() summary got %q, want %q", lines[1], want)
+	}
+}
+
+func TestRunTTY(t *testing.T) {
+	var out bytes.Buffer
+	b := NewBar(&out, "")
+	b.TTY = true
+	b.Width = 40
+	ch := make(chan progressio.Progress, 1)
+	ch <- progressio.Progress{Transferred: 10, TotalSize: 100, Percent: 10, Err: errors.New("broken pipe")}
+	close(ch)
+	b.Run(ch)
+	got := out.String()
+	if !strings.HasPrefix(got, "\r") || !strings.HasSuffix(got, "\r\x1b[Kfailed after 10B: broken pipe\n") {
+		t.Errorf("Run() got %q", got)
+	}
+}
+
+func TestSummaryNoUpdates(t *testing.T) {
+	// The channel was closed without any update
+	if got, want := Summary(progressio.Progress{}), "done: 0B"; got != want {
+		t.Errorf("Summary() got %q, want %q", got, want)
+	}
+}
+
+func TestTerminalWidth(t *testing.T) {
+	t.Setenv("COLUMNS", "123")
+	if got := TerminalWidth(&bytes.Buffer{}); got != 123 {
+		t.Errorf("TerminalWidth() got %d, want 123 from $COLUMNS", got)
+	}
+	t.Setenv("COLUMNS", "")
+	if got := TerminalWidth(&bytes.Buffer{}); got != DefaultWidth {
+		t.Errorf("TerminalWidth() got %d, want %d", got, DefaultWidth)
+	}
+}
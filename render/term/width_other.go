@@ -0,0 +1,11 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package term
+
+import "io"
+
+// ttyWidth returns the width of the terminal w writes to, 0 as it can not be
+// queried on this platform.
+func ttyWidth(w io.Writer) int {
+	return 0
+}